@@ -42,33 +42,29 @@ func (ashm AnalyticSHM) Force() newton.Force {
 	return newton.Force(SingleHooke{K: ashm.K})
 }
 
-func (ashm AnalyticSHM) ForEuler() *newton.System {
+// A system with the oscillator in it's initial state, using the integrator
+// with a time step of dt
+func (ashm AnalyticSHM) For(algo newton.Integrator, dt float64) *newton.System {
 
-	sys := newton.NewSystem(newton.Euler, 1)
+	sys := newton.NewSystem(algo, 1)
 
 	sys.SetForce(ashm.Force())
+	sys.Body(0).SetMass(ashm.M)
 
-	b := sys.Body(0)
-	b.SetMass(ashm.M)
-
-	b.SetNow(ashm.XVAt(0))
+	x, v := ashm.XVAt(0)
+	sys.Initialize([]vect.Vector{x}, []vect.Vector{v}, dt)
 
 	return sys
 }
 
-func (ashm AnalyticSHM) ForVerlet(dt float64) *newton.System {
-
-	sys := newton.NewSystem(newton.Verlet, 1)
-
-	sys.SetForce(ashm.Force())
+func (ashm AnalyticSHM) ForEuler(dt float64) *newton.System {
 
-	b := sys.Body(0)
-	b.SetMass(ashm.M)
+	return ashm.For(newton.Euler, dt)
+}
 
-	b.Shift(ashm.XVAt(-dt))
-	b.Shift(ashm.XVAt(0))
+func (ashm AnalyticSHM) ForVerlet(dt float64) *newton.System {
 
-	return sys
+	return ashm.For(newton.Verlet, dt)
 }
 
 func (ashm AnalyticSHM) DataHeader() {
@@ -84,10 +80,12 @@ func (ashm AnalyticSHM) Run(dt float64, steps int) {
 
 	ashm.DataHeader()
 
-	eulerState := ashm.ForEuler()
+	eulerState := ashm.ForEuler(dt)
 	verletState := ashm.ForVerlet(dt)
 
-	for t := 0.0; steps > 0; {
+	for eulerState.StepIndex() < steps {
+
+		t := eulerState.Time()
 
 		fmt.Printf("%f ", t)
 
@@ -100,9 +98,6 @@ func (ashm AnalyticSHM) Run(dt float64, steps int) {
 		verletState.Step(dt)
 
 		fmt.Println()
-
-		t += dt
-		steps--
 	}
 }
 
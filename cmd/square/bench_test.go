@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+// Steps a 100x100 grid pulled by a uniform force. The springs are left out, so
+// that the time goes to integrating and accessing the bodies' state.
+func BenchmarkStep100x100(b *testing.B) {
+
+	rect := NewRect(100, 100)
+	rect.SetParallel(1)
+	rect.SetForce(ConstForce(vect.UnitZ))
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		rect.Step(0.05)
+	}
+}
+
+func BenchmarkNewRect100x100(b *testing.B) {
+
+	for i := 0; i < b.N; i++ {
+
+		NewRect(100, 100)
+	}
+}
@@ -5,11 +5,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
 
 	"github.com/szabba/md/newton"
@@ -27,6 +29,7 @@ func (f ConstForce) Accel(bs []*newton.Body, i int, dt float64) (a vect.Vector)
 type ParticleRect struct {
 	*newton.System
 	rows, cols int
+	periodic   bool
 }
 
 // Creates a rectangular grid of particles
@@ -40,21 +43,22 @@ func NewRect(rows, cols int) *ParticleRect {
 
 	rect.System = newton.NewSystem(newton.Verlet, rows*cols)
 
-	for i := 0; i < rect.Bodies(); i++ {
-
-		b := rect.Body(i)
+	xs := make([]vect.Vector, rect.Bodies())
+	vs := make([]vect.Vector, rect.Bodies())
 
-		b.SetMass(1)
+	for i := range xs {
 
-		pos := rect.RestingPosition(i)
-
-		b.Shift(pos, vect.Zero)
-		b.Shift(pos, vect.Zero)
+		rect.Body(i).SetMass(1)
 
+		xs[i] = rect.RestingPosition(i)
 	}
 
 	rect.SetForce(ConstForce(vect.Zero))
 
+	// With no force acting, the particles at rest stay put whatever the time
+	// step
+	rect.Initialize(xs, vs, 0)
+
 	return rect
 }
 
@@ -74,20 +78,69 @@ func (rect *ParticleRect) RestingPosition(ith int) vect.Vector {
 	)
 }
 
+// The particles on the edges of the rectangle
+func (rect *ParticleRect) Edges() newton.Selection {
+
+	var edges newton.Indices
+
+	for i := 0; i < rect.Bodies(); i++ {
+
+		row, col := rect.RowAndColumn(i)
+
+		if row == 0 || row == rect.rows-1 || col == 0 || col == rect.cols-1 {
+
+			edges = append(edges, i)
+		}
+	}
+
+	return edges
+}
+
 // Dimmensions of the rectangle
 func (rect *ParticleRect) Size() (rows, cols int) {
 
 	return rect.rows, rect.cols
 }
 
+// Make the rectangle periodic in x and y, so that the particles on opposite
+// edges become neighbours
+//
+// Must be called before the Hooke's force is prepared.
+func (rect *ParticleRect) SetPeriodic() {
+
+	rect.periodic = true
+
+	rect.SetBox(newton.NewOrthorhombic(
+		vect.NewVector(float64(rect.rows), float64(rect.cols), 0),
+	))
+}
+
+// Are a and b next to each other in a row of n?
+func (rect *ParticleRect) adjacent(a, b, n int) bool {
+
+	if a-1 == b || b == a+1 {
+
+		return true
+	}
+
+	if !rect.periodic || n <= 2 {
+
+		return false
+	}
+
+	d := (a - b + n) % n
+
+	return d == 1 || d == n-1
+}
+
 // Are the i-th and j-th particles neighbours?
 func (rect *ParticleRect) Neighbours(i, j int) bool {
 
 	xI, yI := rect.RowAndColumn(i)
 	xJ, yJ := rect.RowAndColumn(j)
 
-	nearInX := xI-1 == xJ || xJ == xI+1
-	nearInY := yI-1 == yJ || yJ == yI+1
+	nearInX := rect.adjacent(xI, xJ, rect.rows)
+	nearInY := rect.adjacent(yI, yJ, rect.cols)
 
 	sameX := xI == xJ
 	sameY := yI == yJ
@@ -118,7 +171,7 @@ func (rect *ParticleRect) Hooke(k float64) newton.Force {
 		}
 	}
 
-	return h
+	return &h
 }
 
 // Is the i-th particle near the center in it's resting position?
@@ -159,6 +212,12 @@ func (rect *ParticleRect) ExceptCenter() []int {
 	return rest
 }
 
+// The particles near the center of the rectangle in their resting positions
+func (rect *ParticleRect) Center() newton.Selection {
+
+	return newton.Not(newton.Indices(rect.ExceptCenter()))
+}
+
 // Prepare a constant force that only affects the center of the rectangle (ie
 // ignores everything except it)
 //
@@ -166,39 +225,96 @@ func (rect *ParticleRect) ExceptCenter() []int {
 // The force applied per particle will be divided by this number.
 func (rect *ParticleRect) CentralPull(pull vect.Vector) newton.Force {
 
-	ignored := rect.ExceptCenter()
+	return rect.Pull(pull, 0, rect.Center())
+}
 
-	pulled := float64(rect.Bodies() - len(ignored))
+// Prepare a force like CentralPull, that oscillates sinusoidally with the
+// given angular frequency
+func (rect *ParticleRect) OscillatingPull(pull vect.Vector, omega float64) newton.Force {
 
-	return newton.NewPicky(ConstForce(pull.Scale(1/pulled)), ignored...)
+	return rect.Pull(pull, omega, rect.Center())
 }
 
-// Runs the simulation for the given number of steps at a time step of dt
-// printing to writeTo
-func (rect *ParticleRect) Run(writeTo io.Writer, dt float64, steps int) {
+// Prepare a force pulling the selected particles, that oscillates
+// sinusoidally with the given angular frequency unless it is zero
+//
+// The force applied per particle is divided by the number of particles
+// selected when the force is created.
+func (rect *ParticleRect) Pull(pull vect.Vector, omega float64, pulled newton.Selection) newton.Force {
 
-	format := &Formatter{rect: rect, writeTo: writeTo}
+	n := len(rect.Select(pulled))
+	if n == 0 {
 
-	format.Header()
+		return ConstForce(vect.Zero)
+	}
 
-	for i := 0; i < steps; i++ {
+	var f newton.Force = ConstForce(pull.Scale(1 / float64(n)))
 
-		format.Frame()
+	if omega != 0 {
 
-		rect.Step(dt)
+		f = newton.DrivenForce{
+			Clock:  rect,
+			Force:  pull.Scale(1 / float64(n)),
+			Signal: newton.Sine{Omega: omega},
+		}
 	}
+
+	return newton.NewPickyIgnoring(f, newton.Not(pulled))
+}
+
+// Runs the simulation for the given number of steps at a time step of dt
+// printing a header and then the shown particles to writeTo after each step
+//
+// A simulation starting from step zero also gets a frame of the initial state
+// before the first step, so steps+1 frames in all. One continued from a
+// checkpoint does not repeat the frame of the restored state, since the run
+// that saved it already printed it. The frames of both runs together show
+// every step once.
+//
+// It stops early when the context is cancelled.
+func (rect *ParticleRect) Run(
+	ctx context.Context, writeTo io.Writer, show newton.Selection,
+	dt float64, steps int,
+) error {
+
+	format := &Formatter{rect: rect, writeTo: writeTo, show: show}
+
+	format.Header()
+
+	rect.AddObserver(1, format)
+
+	return rect.System.Run(ctx, dt, steps)
 }
 
 // An output formatting type
 type Formatter struct {
 	rect    *ParticleRect
 	writeTo io.Writer
+	// The particles written out. All of them when nil.
+	show newton.Selection
+}
+
+// The indices of the particles written out
+func (f Formatter) shown() newton.Selection {
+
+	if f.show == nil {
+
+		return newton.All
+	}
+
+	return f.show
 }
 
-// Formats a data header
+// Formats a data header. The comment line names the units of the columns.
 func (f Formatter) Header() {
 
-	fmt.Fprintf(f.writeTo, "%d\n\n", f.rect.Bodies())
+	units := f.rect.Units()
+
+	fmt.Fprintf(
+		f.writeTo, "%d\nunits=%s x[%s] v[%s]\n",
+		len(f.rect.Select(f.shown())), units.Name,
+		units.Symbol(newton.LengthUnit), units.Symbol(newton.VelocityUnit),
+	)
 }
 
 var (
@@ -207,14 +323,18 @@ var (
 	Z = vect.NewVector(0, 0, 1)
 )
 
-// Formats the description of ball states
-func (f Formatter) Frame() {
+// Writes out a frame whenever the system is observed
+func (f Formatter) Observe(view newton.View) {
 
-	for i := 0; i < f.rect.Bodies(); i++ {
+	f.Frame(view)
+}
 
-		b := f.rect.Body(i)
+// Formats the description of ball states
+func (f Formatter) Frame(view newton.View) {
+
+	for _, i := range view.Select(f.shown()) {
 
-		x, v := b.Now()
+		x, v := view.X(i), view.V(i)
 
 		fmt.Fprintf(
 			f.writeTo, "%d %f %f %f %f %f %f\n", i,
@@ -226,6 +346,56 @@ func (f Formatter) Frame() {
 	fmt.Fprintf(f.writeTo, "\n")
 }
 
+// Continue the simulation from a checkpoint file
+func (rect *ParticleRect) Restore(path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+
+		return err
+	}
+	defer f.Close()
+
+	return rect.ReadCheckpoint(f)
+}
+
+// An observer saving checkpoints to a file. The previous checkpoint is only
+// replaced once the new one is completely written.
+type Checkpointer string
+
+func (path Checkpointer) Observe(view newton.View) {
+
+	if err := path.save(view); err != nil {
+
+		log.Print(err.Error())
+	}
+}
+
+func (path Checkpointer) save(view newton.View) error {
+
+	tmp := string(path) + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+
+		return err
+	}
+
+	err = view.WriteCheckpoint(f)
+
+	if closeErr := f.Close(); err == nil {
+
+		err = closeErr
+	}
+
+	if err != nil {
+
+		return err
+	}
+
+	return os.Rename(tmp, string(path))
+}
+
 const usage string = `Usage of %s:
 
 	Simulate a rectangular surface made of particles interconnected with
@@ -251,9 +421,13 @@ func Help() {
 func main() {
 
 	var (
-		usage    bool
-		p, k, dt float64
-		steps    int
+		usage, periodic, clamp bool
+		p, k, dt, gamma, omega float64
+		steps, workers         int
+		pulled, show           string
+		checkpoint, restart    string
+		unitsName              string
+		every                  int
 	)
 
 	log.SetFlags(0)
@@ -262,9 +436,35 @@ func main() {
 		&p, "pull", 1,
 		"Magnitude of the vertical pulling force. When negative, the force pulls down.",
 	)
+	flag.Float64Var(
+		&omega, "omega", 0,
+		"Angular frequency of the pulling force. Zero means the pull is constant.",
+	)
 	flag.Float64Var(&dt, "dt", 0.05, "Time step")
 	flag.Float64Var(&k, "k", 1, "Hooke's constant")
-	flag.IntVar(&steps, "steps", 5, "Simulation steps to perform")
+	flag.Float64Var(
+		&gamma, "damping", 0,
+		"Linear damping coefficient. Lets the rectangle settle in a steady state under the pull.",
+	)
+	flag.IntVar(&steps, "steps", 5, "Simulation steps to perform, counting those before a restart")
+	flag.StringVar(&checkpoint, "checkpoint", "", "File to save checkpoints to")
+	flag.IntVar(&every, "checkpoint-every", 1000, "Steps between checkpoints")
+	flag.StringVar(&restart, "restart", "", "Checkpoint file to continue the simulation from")
+	flag.StringVar(
+		&unitsName, "units", "reduced",
+		"Units of all the quantities: reduced, real, metal or si",
+	)
+	flag.IntVar(
+		&workers, "workers", 0,
+		"Goroutines computing forces. Zero means as many as GOMAXPROCS.",
+	)
+	flag.BoolVar(&periodic, "periodic", false, "Make the rectangle periodic in x and y")
+	flag.BoolVar(&clamp, "clamped", false, "Keep the edges of the rectangle in place, like a drumhead")
+	flag.StringVar(
+		&pulled, "pulled", "",
+		"Selection of the particles pulled, eg. 'within 2 of 5,5,0'. Empty means the center.",
+	)
+	flag.StringVar(&show, "show", "all", "Selection of the particles written out")
 	flag.BoolVar(&usage, "help", false, "Print usage string")
 
 	flag.Parse()
@@ -297,9 +497,65 @@ func main() {
 			log.Fatal(err.Error())
 		}
 
+		units, ok := newton.UnitsNamed(unitsName)
+		if !ok {
+
+			log.Fatalf("Unknown units %q", unitsName)
+		}
+
 		rect := NewRect(rows, cols)
+		rect.SetUnits(units)
+		rect.SetParallel(workers)
+		if periodic {
+
+			rect.SetPeriodic()
+		}
+		pulledSel := rect.Center()
+		if pulled != "" {
+
+			pulledSel, err = newton.ParseSelection(pulled)
+			if err != nil {
+
+				log.Fatal(err.Error())
+			}
+		}
+		showSel, err := newton.ParseSelection(show)
+		if err != nil {
+
+			log.Fatal(err.Error())
+		}
+
+		if restart != "" {
+
+			if err := rect.Restore(restart); err != nil {
+
+				log.Fatal(err.Error())
+			}
+		}
+
+		if clamp {
+
+			rect.Freeze(rect.Edges())
+		}
+
 		rect.AddForce(rect.Hooke(k))
-		rect.AddForce(rect.CentralPull(vect.UnitZ.Scale(p)))
-		rect.Run(os.Stdout, dt, steps)
+		rect.AddForce(rect.Pull(vect.UnitZ.Scale(p), omega, pulledSel))
+		if gamma != 0 {
+
+			rect.AddForce(newton.LinearDrag{Gamma: gamma})
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if checkpoint != "" {
+
+			rect.AddObserver(every, Checkpointer(checkpoint))
+		}
+
+		if err := rect.Run(ctx, os.Stdout, showSel, dt, steps-rect.StepIndex()); err != nil {
+
+			log.Print(err.Error())
+		}
 	}
 }
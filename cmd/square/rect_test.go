@@ -5,6 +5,9 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +39,58 @@ func TestRowAndColumn(t *testing.T) {
 		}
 	}
 }
+
+func TestPeriodicNeighbours(t *testing.T) {
+
+	rect := NewRect(4, 5)
+
+	first, lastInRow := 0, 3
+	if rect.Neighbours(first, lastInRow) {
+
+		t.Fatalf("%d and %d should not be neighbours in an open rectangle", first, lastInRow)
+	}
+
+	rect.SetPeriodic()
+
+	lastInCol := 16
+	for _, other := range []int{lastInRow, lastInCol} {
+
+		if !rect.Neighbours(first, other) {
+
+			t.Errorf("%d and %d should be neighbours in a periodic rectangle", first, other)
+		}
+	}
+
+	if rect.Neighbours(first, 5) {
+
+		t.Errorf("%d and %d should not be neighbours in a periodic rectangle", first, 5)
+	}
+}
+
+func TestRunFrames(t *testing.T) {
+
+	rect := NewRect(2, 2)
+
+	frames := func(steps int) int {
+
+		var out bytes.Buffer
+
+		if err := rect.Run(context.Background(), &out, nil, 0.05, steps); err != nil {
+
+			t.Fatal(err)
+		}
+
+		return strings.Count(out.String(), "\n\n")
+	}
+
+	if n := frames(3); n != 4 {
+
+		t.Errorf("a fresh run of 3 steps should print 4 frames, not %d", n)
+	}
+
+	// Like one restarted from a checkpoint
+	if n := frames(2); n != 2 {
+
+		t.Errorf("a continued run of 2 steps should print 2 frames, not %d", n)
+	}
+}
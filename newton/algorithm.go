@@ -10,6 +10,9 @@ import (
 )
 
 // An integrator algorithm
+//
+// Integrators keep the velocity at the time of the latest position up to date
+// (see Body.VLatest), so forces may depend on it.
 type Integrator interface {
 	// The total number of states kept at once by the algorithm
 	StateLen() int
@@ -20,6 +23,38 @@ type Integrator interface {
 	Integrate(b *Body, a vect.Vector, dt float64)
 }
 
+// An integrator that knows how to fill in the history it needs from a single
+// state of a body
+type Bootstrapper interface {
+	Integrator
+	// Fill in the history of the body so that it's latest position and
+	// velocity are x and v and it's acceleration there is a
+	Bootstrap(b *Body, x, v, a vect.Vector, dt float64)
+}
+
+// Fill in the history of the body that the integrator needs, so that it's
+// latest position and velocity are x and v and it's acceleration there is a
+//
+// Integrators that are not Bootstrappers get a history of uniform motion.
+// The coordinates along which the body is frozen stay those of x, with no
+// velocity.
+func Bootstrap(algo Integrator, b *Body, x, v, a vect.Vector, dt float64) {
+
+	if bs, ok := algo.(Bootstrapper); ok {
+
+		bs.Bootstrap(b, x, v, a, dt)
+
+	} else {
+
+		b.moveUniformly(x, v, dt)
+	}
+
+	if b.props.frozen != [3]bool{} {
+
+		b.pin(components(x))
+	}
+}
+
 var (
 	Euler  Integrator = euler{}
 	Verlet Integrator = verlet{}
@@ -45,6 +80,11 @@ func (_ euler) Integrate(b *Body, a vect.Vector, dt float64) {
 	b.Shift(x, v)
 }
 
+func (_ euler) Bootstrap(b *Body, x, v, a vect.Vector, dt float64) {
+
+	b.Xs[0], b.Vs[0] = x, v
+}
+
 type verlet struct{}
 
 func (_ verlet) StateLen() int {
@@ -57,24 +97,99 @@ func (_ verlet) CurrentAt() int {
 
 func (_ verlet) Integrate(b *Body, a vect.Vector, dt float64) {
 
-	xPast := b.XNow()
-	xNext := b.XAfter(1).Scale(2).Minus(xPast).Plus(a.Scale(math.Pow(dt, 2)))
+	xPast, x := b.XNow(), b.XAfter(1)
+	xNext := x.Scale(2).Minus(xPast).Plus(a.Scale(math.Pow(dt, 2)))
+
+	// The velocity at the newest position is only known after the next step.
+	// Until then, it is predicted from a backward difference corrected with
+	// the current acceleration, so that velocity dependent forces see an
+	// estimate accurate to the second order in dt.
+	vNext := xNext.Minus(x).Scale(1 / dt).Plus(a.Scale(dt / 2))
 
-	b.Shift(xNext, vect.Zero)
+	b.Shift(xNext, vNext)
 	b.SetVNow(xNext.Minus(xPast).Scale(1 / (2 * dt)))
 }
 
-func Step(algo Integrator, bs []*Body, f Force, dt float64) {
+// Fills in the position a step before with a second order Taylor expansion
+// backwards in time
+func (_ verlet) Bootstrap(b *Body, x, v, a vect.Vector, dt float64) {
 
-	as := make([]vect.Vector, len(bs))
+	b.Xs[0], b.Vs[0] = x, v
+
+	b.Xs[1] = x.Minus(v.Scale(dt)).Plus(a.Scale(dt * dt / 2))
+	b.Vs[1] = v.Minus(a.Scale(dt))
+}
+
+// The SLLOD equations of motion for a planar Couette flow
+//
+//	u_x = ShearRate y
+//
+// integrated with the semi-implicit Euler method. The velocities the bodies
+// keep are peculiar -- relative to the flow. Use with a LeesEdwards box.
+type SLLOD struct {
+	ShearRate float64
+}
+
+func (_ SLLOD) StateLen() int {
+
+	return 1
+}
+
+func (_ SLLOD) CurrentAt() int {
 
-	for i, _ := range bs {
+	return 0
+}
+
+func (s SLLOD) Integrate(b *Body, a vect.Vector, dt float64) {
+
+	x0, v0 := b.Now()
+
+	a = a.Minus(vect.UnitX.Scale(s.ShearRate * v0.Dot(vect.UnitY)))
+
+	v := v0.Plus(a.Scale(dt))
+	flow := vect.UnitX.Scale(s.ShearRate * x0.Dot(vect.UnitY))
+	x := x0.Plus(v.Plus(flow).Scale(dt))
+
+	b.Shift(x, v)
+}
+
+func (_ SLLOD) Bootstrap(b *Body, x, v, a vect.Vector, dt float64) {
+
+	b.Xs[0], b.Vs[0] = x, v
+}
 
-		as[i] = f.Accel(bs, i, dt)
+// Advance the body by a step, leaving the coordinates along which it is frozen
+// alone, and wrap it back into it's box
+func integrate(algo Integrator, b *Body, a vect.Vector, dt float64) {
+
+	switch b.props.frozen {
+
+	case [3]bool{true, true, true}:
+		return
+
+	case [3]bool{}:
+		algo.Integrate(b, a, dt)
+
+	default:
+		x := components(b.Xs[0])
+
+		algo.Integrate(b, a, dt)
+		b.pin(x)
 	}
 
+	b.wrap()
+}
+
+func Step(algo Integrator, bs []*Body, f Force, dt float64) {
+
+	prepare(f, bs)
+
+	as := make([]vect.Vector, len(bs))
+
+	addAccels(f, bs, as, dt, nil)
+
 	for i, body := range bs {
 
-		algo.Integrate(body, as[i], dt)
+		integrate(algo, body, as[i], dt)
 	}
 }
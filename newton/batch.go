@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// Totals a batch force can compute along with the accelerations
+type Tally struct {
+	Energy float64
+	Virial Tensor
+}
+
+// A force that computes the accelerations of all the bodies in one pass
+//
+// Pair forces can then compute each interaction once and apply it to both
+// bodies.
+type BatchForce interface {
+	Force
+	// Adds the acceleration of each body to as. Unless tally is nil, the
+	// force's energy and virial are added to it.
+	AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally)
+}
+
+// A batch force that can split it's pass into parts computed concurrently
+type SplitForce interface {
+	BatchForce
+	// Adds the accelerations due to the part-th of parts shares of the
+	// interactions. Together the parts add what AddAccels does. Once the force
+	// is prepared, different parts may be computed concurrently, each into
+	// it's own as and tally.
+	AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally)
+}
+
+// Adds the accelerations due to f to as, in one pass if f is a BatchForce and
+// body by body otherwise. Unless tally is nil, the energy and virial of f are
+// added to it, as far as f can tell them.
+func addAccels(f Force, bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	if bf, ok := f.(BatchForce); ok {
+
+		bf.AddAccels(bs, as, dt, tally)
+
+		return
+	}
+
+	for i := range bs {
+
+		as[i] = as[i].Plus(f.Accel(bs, i, dt))
+	}
+
+	addTally(f, bs, tally)
+}
+
+// Adds the part-th of parts shares of the accelerations due to f to as. A
+// batch force that can't be split does all of it's pass in the first part,
+// other forces go over every parts-th body.
+func addAccelsPart(f Force, bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	switch f := f.(type) {
+
+	case SplitForce:
+
+		f.AddAccelsPart(bs, as, part, parts, dt, tally)
+
+	case BatchForce:
+
+		if part == 0 {
+
+			f.AddAccels(bs, as, dt, tally)
+		}
+
+	default:
+
+		for i := part; i < len(bs); i += parts {
+
+			as[i] = as[i].Plus(f.Accel(bs, i, dt))
+		}
+
+		if part == 0 {
+
+			addTally(f, bs, tally)
+		}
+	}
+}
+
+// Adds the energy and virial of a force to the tally, when it can tell them
+func addTally(f Force, bs []*Body, tally *Tally) {
+
+	if tally == nil {
+
+		return
+	}
+
+	if p, ok := f.(Potential); ok {
+
+		tally.Energy += p.Energy(bs)
+	}
+
+	if v, ok := f.(Virial); ok {
+
+		tally.Virial = tally.Virial.Plus(v.Virial(bs))
+	}
+}
+
+// Adds the accelerations due to a central force between the i-th and j-th
+// body, separated by d, with an energy derivative dUdr. The tally gets the
+// energy u.
+func addPairAccels(
+	bs []*Body, as []vect.Vector, i, j int, d vect.Vector,
+	u, dUdr float64, tally *Tally,
+) {
+
+	f := d.Unit().Scale(dUdr)
+
+	as[i] = as[i].Plus(f.Scale(1 / bs[i].Mass()))
+	as[j] = as[j].Minus(f.Scale(1 / bs[j].Mass()))
+
+	if tally != nil {
+
+		tally.Energy += u
+		tally.Virial = tally.Virial.Plus(pairVirial(d, dUdr))
+	}
+}
@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+// Checks a batch force gives every body the same acceleration as Accel and
+// tallies the same energy as Energy
+func batchMatchesAccel(name string, f BatchForce, bs []*Body, t *testing.T) {
+
+	prepare(f, bs)
+
+	as := make([]vect.Vector, len(bs))
+	tally := new(Tally)
+
+	f.AddAccels(bs, as, 0.01, tally)
+
+	for i := range bs {
+
+		if a := f.Accel(bs, i, 0.01); a.Minus(as[i]).Norm() > 1e-9*math.Max(1, a.Norm()) {
+
+			t.Errorf("%s: body %d should accelerate by %v not %v", name, i, a, as[i])
+		}
+	}
+
+	if p, ok := f.(Potential); ok {
+
+		if u := p.Energy(bs); math.Abs(u-tally.Energy) > 1e-9*math.Max(1, math.Abs(u)) {
+
+			t.Errorf("%s: the tallied energy should be %g not %g", name, u, tally.Energy)
+		}
+	}
+
+	sf, ok := f.(SplitForce)
+	if !ok {
+
+		return
+	}
+
+	parts := make([]vect.Vector, len(bs))
+	for part := 0; part < 3; part++ {
+
+		sf.AddAccelsPart(bs, parts, part, 3, 0.01, nil)
+	}
+
+	for i := range bs {
+
+		if parts[i].Minus(as[i]).Norm() > 1e-9*math.Max(1, as[i].Norm()) {
+
+			t.Errorf("%s: the parts should add up to %v not %v for body %d", name, as[i], parts[i], i)
+		}
+	}
+}
+
+func TestBatchForcesMatchAccel(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(3))
+
+	bs := randomBodies(20, 4, rng)
+	for _, b := range bs {
+
+		b.SetMass(1 + rng.Float64())
+		b.Shift(b.XLatest(), vect.NewVector(rng.Float64(), rng.Float64(), 0))
+	}
+
+	springs := make([][]Spring, len(bs))
+	for i := range springs {
+
+		springs[i] = make([]Spring, len(bs))
+	}
+	for i := range bs {
+		for j := i + 1; j < len(bs); j++ {
+
+			s := Spring{K: rng.Float64(), L0: rng.Float64(), Damping: 0.1}
+			springs[i][j], springs[j][i] = s, s
+		}
+	}
+
+	bonds := Bonds{
+		{I: 0, J: 1, Potential: Morse{D: 1, A: 2, R0: 1}},
+		{I: 1, J: 2, Potential: Harmonic{K: 3, R0: 1}, Damping: 0.5},
+		{I: 5, J: 3, Potential: Harmonic{K: 1, R0: 2}},
+	}
+
+	lj := &PairForce{
+		Potential: Shifted(LennardJones{Epsilon: 1, Sigma: 0.5}, 1.5),
+		Cutoff:    1.5,
+	}
+
+	hooke := Hooke{Springs: springs}
+
+	batchMatchesAccel("Hooke", hooke, bs, t)
+	batchMatchesAccel("Bonds", bonds, bs, t)
+	batchMatchesAccel("PairForce", lj, bs, t)
+	batchMatchesAccel("SumForce", SumForce{hooke, bonds, lj}, bs, t)
+	batchMatchesAccel("PickyForce", NewPicky(SumForce{bonds, lj}, 1, 4).(BatchForce), bs, t)
+
+	picky, tally := NewPicky(lj, 1, 4).(BatchForce), new(Tally)
+	prepare(picky, bs)
+	picky.AddAccels(bs, make([]vect.Vector, len(bs)), 0.01, tally)
+
+	if u := lj.Energy(bs); math.Abs(u-tally.Energy) > 1e-9*math.Max(1, math.Abs(u)) {
+
+		t.Errorf("PickyForce: the tallied energy should be %g not %g", u, tally.Energy)
+	}
+}
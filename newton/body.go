@@ -8,35 +8,209 @@ import (
 	"github.com/szabba/md/vect"
 )
 
+// A body's positions and velocities at the few latest steps an integrator
+// needs, newest first, it's mass and other properties
+//
+// The bodies of a System are views into arrays shared by all of them, so
+// their states are contiguous in memory.
 type Body struct {
 	Xs, Vs []vect.Vector
-	mass   float64
+	mass   *float64
+	props  *properties
 	currAt int
+
+	box   Box
+	image [3]int
 }
 
 // Constructs a body of specified mass suitable for working with the integrator
 func NewBody(algo Integrator) *Body {
 
-	b := new(Body)
+	n := algo.StateLen()
+
+	b := bodyIn(
+		algo, make([]vect.Vector, n), make([]vect.Vector, n),
+		new(float64), new(properties),
+	)
+
+	return &b
+}
+
+// A body keeping it's state in the given storage
+func bodyIn(
+	algo Integrator, xs, vs []vect.Vector, mass *float64, props *properties,
+) Body {
+
+	return Body{
+		Xs: xs, Vs: vs, mass: mass, props: props,
+		currAt: algo.CurrentAt(),
+		box:    Open,
+	}
+}
 
-	b.Xs = make([]vect.Vector, algo.StateLen())
-	b.Vs = make([]vect.Vector, algo.StateLen())
+// Give the body storage of it's own, with a copy of it's current state
+func (b *Body) detach() {
 
-	b.currAt = algo.CurrentAt()
+	b.Xs = append([]vect.Vector(nil), b.Xs...)
+	b.Vs = append([]vect.Vector(nil), b.Vs...)
 
-	return b
+	m := *b.mass
+	b.mass = &m
+
+	props := *b.props
+	b.props = &props
 }
 
 // Set a body's mass
 func (b *Body) SetMass(m float64) {
 
-	b.mass = m
+	*b.mass = m
 }
 
 // Give a body's mass
 func (b *Body) Mass() float64 {
 
-	return b.mass
+	return *b.mass
+}
+
+// The identifier of the body within it's system. It does not change when other
+// bodies are added or removed.
+func (b *Body) ID() int {
+
+	return b.props.id
+}
+
+// The species of the body, if it has one
+func (b *Body) Species() *Species {
+
+	return b.props.species
+}
+
+// Make the body one of the species, taking the mass, charge and radius of the
+// species. They can be changed for the body alone afterwards.
+func (b *Body) SetSpecies(s *Species) {
+
+	b.props.species = s
+
+	b.SetMass(s.Mass)
+	b.SetCharge(s.Charge)
+	b.SetRadius(s.Radius)
+}
+
+// The name of the body's species, or an empty string if it has none
+func (b *Body) SpeciesName() string {
+
+	if b.props.species == nil {
+
+		return ""
+	}
+
+	return b.props.species.Name
+}
+
+// Give a body's charge
+func (b *Body) Charge() float64 {
+
+	return b.props.charge
+}
+
+// Set a body's charge
+func (b *Body) SetCharge(q float64) {
+
+	b.props.charge = q
+}
+
+// Give a body's radius
+func (b *Body) Radius() float64 {
+
+	return b.props.radius
+}
+
+// Set a body's radius
+func (b *Body) SetRadius(r float64) {
+
+	b.props.radius = r
+}
+
+// Stop the body from moving along the given axes, keeping the coordinates of
+// it's latest position. The velocity along them becomes zero.
+func (b *Body) SetFrozen(x, y, z bool) {
+
+	b.props.frozen = [3]bool{x, y, z}
+
+	b.pin(components(b.Xs[0]))
+}
+
+// Stop the body from moving at all
+func (b *Body) Freeze() {
+
+	b.SetFrozen(true, true, true)
+}
+
+// Let the body move freely again
+func (b *Body) Unfreeze() {
+
+	b.SetFrozen(false, false, false)
+}
+
+// The axes along which the body does not move
+func (b *Body) Frozen() (x, y, z bool) {
+
+	f := b.props.frozen
+
+	return f[0], f[1], f[2]
+}
+
+// Set the frozen coordinates of the whole history to the given ones and the
+// velocities along them to zero
+func (b *Body) pin(at [3]float64) {
+
+	frozen := b.props.frozen
+
+	for k := range b.Xs {
+
+		x, v := components(b.Xs[k]), components(b.Vs[k])
+
+		for axis, f := range frozen {
+
+			if f {
+
+				x[axis], v[axis] = at[axis], 0
+			}
+		}
+
+		b.Xs[k], b.Vs[k] = fromComponents(x), fromComponents(v)
+	}
+}
+
+// Fill the history as if the body had been moving with velocity v in steps of
+// dt, with x being the latest position
+func (b *Body) moveUniformly(x, v vect.Vector, dt float64) {
+
+	for k := range b.Xs {
+
+		b.Xs[k] = x.Minus(v.Scale(float64(k) * dt))
+		b.Vs[k] = v
+	}
+}
+
+// The value of a user defined attribute of the body, and whether it was set
+func (b *Body) Attr(key string) (value interface{}, ok bool) {
+
+	value, ok = b.props.attrs[key]
+
+	return value, ok
+}
+
+// Set a user defined attribute of the body
+func (b *Body) SetAttr(key string, value interface{}) {
+
+	if b.props.attrs == nil {
+
+		b.props.attrs = make(map[string]interface{})
+	}
+
+	b.props.attrs[key] = value
 }
 
 // Put new values of x and v a the beginning of the remembered values
@@ -48,6 +222,26 @@ func (b *Body) Shift(x, v vect.Vector) {
 	Shift(b.Vs, v)
 }
 
+// The most recent position and velocity, at which forces are evaluated
+//
+// For integrators that keep more than one state these can lie ahead of Now.
+func (b *Body) Latest() (x, v vect.Vector) {
+
+	return b.XLatest(), b.VLatest()
+}
+
+// The most recent position
+func (b *Body) XLatest() vect.Vector {
+
+	return b.Xs[0]
+}
+
+// The most recent velocity
+func (b *Body) VLatest() vect.Vector {
+
+	return b.Vs[0]
+}
+
 // Current positon and velocity
 func (b *Body) Now() (x, v vect.Vector) {
 
@@ -159,6 +353,47 @@ func (b *Body) SetVAfter(v vect.Vector, delta int) {
 	b.Vs[b.currAt-delta] = v
 }
 
+// The image of the periodic box the body is in, counting from the one it
+// started in
+func (b *Body) Image() [3]int {
+
+	return b.image
+}
+
+// The current position, as if the body never got wrapped back into the box
+func (b *Body) XUnwrapped() vect.Vector {
+
+	return b.XNow().Plus(b.box.Translation(b.image))
+}
+
+// The latest position, as if the body never got wrapped back into the box
+func (b *Body) xLatestUnwrapped() vect.Vector {
+
+	return b.Xs[0].Plus(b.box.Translation(b.image))
+}
+
+// Move the body back into the box when it's latest position leaves it. The
+// whole history is moved, so that integrators see no jump.
+func (b *Body) wrap() {
+
+	wrapped, image := b.box.Wrap(b.Xs[0])
+	if image == [3]int{} {
+
+		return
+	}
+
+	shift := wrapped.Minus(b.Xs[0])
+	for i := range b.Xs {
+
+		b.Xs[i] = b.Xs[i].Plus(shift)
+	}
+
+	for ax := range image {
+
+		b.image[ax] += image[ax]
+	}
+}
+
 // Shifts all the values in xs by one and puts x at the beginning.
 func Shift(xs []vect.Vector, x vect.Vector) {
 
@@ -0,0 +1,156 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A bond between the I-th and J-th body
+type Bond struct {
+	I, J      int
+	Potential PairPotential
+	// Coefficient of a dashpot parallel to the bond
+	Damping float64
+}
+
+// A force due to a set of bonds
+//
+// Combined with WCA it gives the Kremer-Grest bead-spring model when the bonds
+// are FENE springs.
+type Bonds []Bond
+
+func (bonds Bonds) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	f := vect.Zero
+
+	for _, bond := range bonds {
+
+		j := bond.Other(i)
+		if j < 0 {
+			continue
+		}
+
+		dir, r := separation(bs, i, j).UnitAndNorm()
+
+		f = f.Plus(dir.Scale(bond.Potential.Derivative(r)))
+
+		if bond.Damping != 0 {
+
+			f = f.Plus(dashpot(bs, i, j, dir, bond.Damping))
+		}
+	}
+
+	return f.Scale(1 / bs[i].Mass())
+}
+
+// The potential energy of all the bonds
+func (bonds Bonds) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for _, bond := range bonds {
+
+		u += bond.Potential.Energy(separation(bs, bond.I, bond.J).Norm())
+	}
+
+	return u
+}
+
+// The virial of the bonds, without the dashpots
+func (bonds Bonds) Virial(bs []*Body) Tensor {
+
+	var w Tensor
+
+	for _, bond := range bonds {
+
+		d := separation(bs, bond.I, bond.J)
+
+		w = w.Plus(pairVirial(d, bond.Potential.Derivative(d.Norm())))
+	}
+
+	return w
+}
+
+// Adds the accelerations due to all the bonds, going over each of them once
+func (bonds Bonds) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	bonds.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Adds the accelerations due to every parts-th bond
+func (bonds Bonds) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	for k := part; k < len(bonds); k += parts {
+
+		bond := bonds[k]
+
+		i, j := bond.I, bond.J
+
+		d := separation(bs, i, j)
+		r := d.Norm()
+
+		p := bond.Potential
+		addPairAccels(bs, as, i, j, d, p.Energy(r), p.Derivative(r), tally)
+
+		if bond.Damping != 0 {
+
+			f := dashpot(bs, i, j, d.Unit(), bond.Damping)
+
+			as[i] = as[i].Plus(f.Scale(1 / bs[i].Mass()))
+			as[j] = as[j].Minus(f.Scale(1 / bs[j].Mass()))
+		}
+	}
+}
+
+// Follows a body being added
+//
+// Only *Bonds can follow the bodies of a System.
+func (bonds *Bonds) BodyAdded(i int) {
+
+	for k := range *bonds {
+
+		bond := &(*bonds)[k]
+
+		bond.I = indexAfterAdding(bond.I, i)
+		bond.J = indexAfterAdding(bond.J, i)
+	}
+}
+
+// Follows a body being removed, breaking all of it's bonds
+//
+// Only *Bonds can follow the bodies of a System.
+func (bonds *Bonds) BodyRemoved(i int) {
+
+	kept := (*bonds)[:0]
+
+	for _, bond := range *bonds {
+
+		if bond.Other(i) >= 0 {
+			continue
+		}
+
+		bond.I = indexAfterRemoving(bond.I, i)
+		bond.J = indexAfterRemoving(bond.J, i)
+
+		kept = append(kept, bond)
+	}
+
+	*bonds = kept
+}
+
+// The index of the body the i-th one is bonded to. When the bond doesn't
+// involve the i-th body, returns -1.
+func (bond Bond) Other(i int) int {
+
+	switch i {
+	case bond.I:
+		return bond.J
+	case bond.J:
+		return bond.I
+	}
+
+	return -1
+}
@@ -0,0 +1,295 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// The space the bodies move in
+type Box interface {
+	// The vector from x to the nearest image of y
+	Separation(x, y vect.Vector) vect.Vector
+	// Moves x into the box. The image is the number of box periods x had to
+	// be moved back by along each of the box's edges.
+	Wrap(x vect.Vector) (wrapped vect.Vector, image [3]int)
+	// The translation taking a point in the box to the given image
+	Translation(image [3]int) vect.Vector
+	// The volume of the box. Infinite when it isn't periodic in all
+	// directions.
+	Volume() float64
+
+	// Coordinates along the directions in which the box repeats, scaled so
+	// that none of them changes by more than the distance moved
+	coordinates(x vect.Vector) [3]float64
+	// The periods of the coordinates, zero where the box doesn't repeat
+	periods() [3]float64
+}
+
+// Open space, with no boundaries
+var Open Box = open{}
+
+type open struct{}
+
+func (_ open) Separation(x, y vect.Vector) vect.Vector {
+
+	return y.Minus(x)
+}
+
+func (_ open) Wrap(x vect.Vector) (vect.Vector, [3]int) {
+
+	return x, [3]int{}
+}
+
+func (_ open) Translation(image [3]int) vect.Vector {
+
+	return vect.Zero
+}
+
+func (_ open) Volume() float64 {
+
+	return math.Inf(1)
+}
+
+func (_ open) coordinates(x vect.Vector) [3]float64 {
+
+	return components(x)
+}
+
+func (_ open) periods() [3]float64 {
+
+	return [3]float64{}
+}
+
+// A rectangular box with a corner at the origin and edges along the axes
+//
+// The box is periodic along the axes for which it has a nonzero length, and
+// open along the others.
+type Orthorhombic struct {
+	lengths [3]float64
+}
+
+// Constructs a rectangular box with the given edge lengths
+func NewOrthorhombic(lengths vect.Vector) *Orthorhombic {
+
+	return &Orthorhombic{lengths: components(lengths)}
+}
+
+// The edge lengths of the box
+func (o *Orthorhombic) Lengths() vect.Vector {
+
+	return fromComponents(o.lengths)
+}
+
+func (o *Orthorhombic) Separation(x, y vect.Vector) vect.Vector {
+
+	d := components(y.Minus(x))
+
+	for ax, l := range o.lengths {
+
+		if l > 0 {
+
+			d[ax] -= l * math.Floor(d[ax]/l+0.5)
+		}
+	}
+
+	return fromComponents(d)
+}
+
+func (o *Orthorhombic) Wrap(x vect.Vector) (wrapped vect.Vector, image [3]int) {
+
+	xs := components(x)
+
+	for ax, l := range o.lengths {
+
+		if l > 0 {
+
+			n := math.Floor(xs[ax] / l)
+
+			xs[ax] -= n * l
+			image[ax] = int(n)
+		}
+	}
+
+	return fromComponents(xs), image
+}
+
+func (o *Orthorhombic) Translation(image [3]int) vect.Vector {
+
+	var t [3]float64
+
+	for ax, l := range o.lengths {
+
+		t[ax] = float64(image[ax]) * l
+	}
+
+	return fromComponents(t)
+}
+
+func (o *Orthorhombic) Volume() float64 {
+
+	v := 1.0
+
+	for _, l := range o.lengths {
+
+		if l == 0 {
+
+			return math.Inf(1)
+		}
+
+		v *= l
+	}
+
+	return v
+}
+
+func (o *Orthorhombic) coordinates(x vect.Vector) [3]float64 {
+
+	return components(x)
+}
+
+func (o *Orthorhombic) periods() [3]float64 {
+
+	return o.lengths
+}
+
+// A parallelepiped box with a corner at the origin, periodic along all three
+// of it's edges
+//
+// The minimum image is found by rounding fractional coordinates, which is
+// exact as long as the box is not too skewed.
+type Triclinic struct {
+	edges [3]vect.Vector
+	// Vectors that give the fractional coordinates along the edges when
+	// dotted with a position
+	reciprocal [3]vect.Vector
+}
+
+// Constructs a box with the given edge vectors
+func NewTriclinic(a, b, c vect.Vector) *Triclinic {
+
+	v := a.Dot(b.Cross(c))
+
+	return &Triclinic{
+		edges: [3]vect.Vector{a, b, c},
+		reciprocal: [3]vect.Vector{
+			b.Cross(c).Scale(1 / v),
+			c.Cross(a).Scale(1 / v),
+			a.Cross(b).Scale(1 / v),
+		},
+	}
+}
+
+// The edge vectors of the box
+func (t *Triclinic) Edges() (a, b, c vect.Vector) {
+
+	return t.edges[0], t.edges[1], t.edges[2]
+}
+
+func (t *Triclinic) Separation(x, y vect.Vector) vect.Vector {
+
+	s := t.fractional(y.Minus(x))
+
+	for ax := range s {
+
+		s[ax] -= math.Floor(s[ax] + 0.5)
+	}
+
+	return t.fromFractional(s)
+}
+
+func (t *Triclinic) Wrap(x vect.Vector) (wrapped vect.Vector, image [3]int) {
+
+	s := t.fractional(x)
+
+	for ax := range s {
+
+		image[ax] = int(math.Floor(s[ax]))
+	}
+
+	return x.Minus(t.Translation(image)), image
+}
+
+func (t *Triclinic) Translation(image [3]int) vect.Vector {
+
+	return t.fromFractional([3]float64{
+		float64(image[0]), float64(image[1]), float64(image[2]),
+	})
+}
+
+func (t *Triclinic) Volume() float64 {
+
+	return math.Abs(t.edges[0].Dot(t.edges[1].Cross(t.edges[2])))
+}
+
+// The fractional coordinates scaled by the distances between opposite faces
+func (t *Triclinic) coordinates(x vect.Vector) [3]float64 {
+
+	s, h := t.fractional(x), t.periods()
+
+	for ax := range s {
+
+		s[ax] *= h[ax]
+	}
+
+	return s
+}
+
+// The distances between opposite faces of the box
+func (t *Triclinic) periods() [3]float64 {
+
+	var h [3]float64
+
+	for ax, r := range t.reciprocal {
+
+		h[ax] = 1 / r.Norm()
+	}
+
+	return h
+}
+
+func (t *Triclinic) fractional(x vect.Vector) [3]float64 {
+
+	var s [3]float64
+
+	for ax, r := range t.reciprocal {
+
+		s[ax] = x.Dot(r)
+	}
+
+	return s
+}
+
+func (t *Triclinic) fromFractional(s [3]float64) vect.Vector {
+
+	x := vect.Zero
+
+	for ax, e := range t.edges {
+
+		x = x.Plus(e.Scale(s[ax]))
+	}
+
+	return x
+}
+
+// The minimum-image vector from the latest position of one body to the latest
+// position of another, in the box of the first one
+func Separation(from, to *Body) vect.Vector {
+
+	return from.box.Separation(from.Xs[0], to.Xs[0])
+}
+
+// The box the bodies are in
+func boxOf(bs []*Body) Box {
+
+	if len(bs) == 0 {
+
+		return Open
+	}
+
+	return bs[0].box
+}
@@ -0,0 +1,84 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func near(a, b vect.Vector) bool {
+
+	return a.Minus(b).Norm() < 1e-9
+}
+
+func TestMinimumImage(t *testing.T) {
+
+	ortho := NewOrthorhombic(vect.NewVector(10, 10, 0))
+	tri := NewTriclinic(
+		vect.NewVector(10, 0, 0),
+		vect.NewVector(2, 10, 0),
+		vect.NewVector(0, 0, 10),
+	)
+
+	cases := []struct {
+		box     Box
+		x, y, d vect.Vector
+	}{
+		{ortho, vect.NewVector(1, 1, 0), vect.NewVector(9, 2, 30), vect.NewVector(-2, 1, 30)},
+		{tri, vect.NewVector(1, 1, 1), vect.NewVector(2, 9, 1), vect.NewVector(-1, -2, 0)},
+		{Open, vect.NewVector(1, 1, 1), vect.NewVector(20, 9, 1), vect.NewVector(19, 8, 0)},
+	}
+
+	for _, c := range cases {
+
+		if d := c.box.Separation(c.x, c.y); !near(d, c.d) {
+
+			t.Errorf("separation from %v to %v should be %v not %v", c.x, c.y, c.d, d)
+		}
+	}
+}
+
+func TestWrappingKeepsUnwrappedPosition(t *testing.T) {
+
+	sys := NewSystem(Verlet, 1)
+	sys.SetForce(LinearDrag{})
+	sys.SetBox(NewTriclinic(
+		vect.NewVector(4, 0, 0),
+		vect.NewVector(1, 4, 0),
+		vect.NewVector(0, 1, 4),
+	))
+
+	b := sys.Body(0)
+	b.SetMass(1)
+
+	v, dt := vect.NewVector(3, -2, 1.5), 0.1
+
+	b.Shift(v.Scale(-dt), v)
+	b.Shift(vect.Zero, v)
+
+	for i := 0; i < 100; i++ {
+
+		sys.Step(dt)
+	}
+
+	expected := v.Scale(float64(99) * dt)
+
+	if !near(b.XUnwrapped(), expected) {
+
+		t.Fatalf("unwrapped position should be %v not %v", expected, b.XUnwrapped())
+	}
+
+	if b.Image() == [3]int{} {
+
+		t.Fatalf("the body should have left the original image")
+	}
+
+	if wrapped, image := sys.Box().Wrap(b.XLatest()); image != [3]int{} || !near(wrapped, b.XLatest()) {
+
+		t.Fatalf("the latest position %v should be in the box", b.XLatest())
+	}
+}
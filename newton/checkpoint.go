@@ -0,0 +1,653 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// The first bytes of every checkpoint
+const checkpointMagic = "newtonCP"
+
+// The version of the checkpoint format written
+const CheckpointVersion = 1
+
+// Something other than the bodies whose state has to survive a checkpoint, eg.
+// a thermostat or a random number generator
+type Checkpointed interface {
+	// The name of the checkpoint section holding the state. It must be unique
+	// within a system.
+	Section() string
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// Have the state of c saved in the system's checkpoints and restored from them
+//
+// The system force is saved without adding it, when it is Checkpointed.
+func (sys *System) AddCheckpointed(c Checkpointed) {
+
+	sys.checkpointed = append(sys.checkpointed, c)
+}
+
+// The parts of the system saved in checkpoints along with the bodies
+func (sys *System) extraSections() []Checkpointed {
+
+	cs := append([]Checkpointed{sys.rng}, sys.checkpointed...)
+
+	for _, nl := range sys.lists {
+
+		cs = append(cs, nl)
+	}
+
+	if c, ok := sys.force.(Checkpointed); ok {
+
+		cs = append(cs, c)
+	}
+
+	return cs
+}
+
+// Writes the whole state of the system: the time, the step index, the full
+// history of every body the integrator keeps, their masses and other
+// properties, the random number generator, the neighbour lists made by the
+// system and the state of everything added with AddCheckpointed.
+//
+// Attributes of the bodies are only saved when they are strings, ints,
+// float64s or bools. Forces, boundaries and the box are part of the set up
+// and are not saved.
+//
+// A checkpoint is a header followed by named sections, so that ones which
+// are not understood can be skipped when reading it.
+func (sys *System) WriteCheckpoint(w io.Writer) error {
+
+	out := new(bytes.Buffer)
+
+	out.WriteString(checkpointMagic)
+	binary.Write(out, binary.LittleEndian, uint32(CheckpointVersion))
+
+	var sec sectionWriter
+
+	sec.str(integratorName(sys.algo))
+	sec.u64(uint64(sys.algo.StateLen()))
+	sec.u64(uint64(len(sys.bodies)))
+	sec.f64(sys.t)
+	sec.i64(int64(sys.step))
+	sec.i64(int64(sys.nextID))
+	sec.to(out, "system")
+
+	for _, b := range sys.bodies {
+
+		sys.writeBody(&sec, b)
+	}
+	sec.to(out, "bodies")
+
+	for _, c := range sys.extraSections() {
+
+		data, err := c.MarshalBinary()
+		if err != nil {
+
+			return fmt.Errorf("checkpoint: section %s: %s", c.Section(), err)
+		}
+
+		sec.buf.Write(data)
+		sec.to(out, c.Section())
+	}
+
+	sec.to(out, "end")
+
+	_, err := out.WriteTo(w)
+
+	return err
+}
+
+func (sys *System) writeBody(sec *sectionWriter, b *Body) {
+
+	p := b.props
+
+	sec.i64(int64(p.id))
+
+	if p.species != nil {
+
+		sec.str(p.species.Name)
+		sec.f64(p.species.Mass)
+		sec.f64(p.species.Charge)
+		sec.f64(p.species.Radius)
+
+	} else {
+
+		sec.str("")
+	}
+
+	sec.f64(b.Mass())
+	sec.f64(p.charge)
+	sec.f64(p.radius)
+
+	for k := range p.frozen {
+
+		sec.flag(p.frozen[k])
+		sec.i64(int64(b.image[k]))
+	}
+
+	for k := range b.Xs {
+
+		sec.vec(b.Xs[k])
+		sec.vec(b.Vs[k])
+	}
+
+	sec.attrs(p.attrs)
+}
+
+// Restores the state of the system from a checkpoint. The system must use
+// the same integrator as the one that was saved.
+//
+// The number of bodies becomes the one saved. Bodies obtained from the system
+// before keep their state, detached from it. The forces have to be set up for
+// the restored bodies -- they are not told about the bodies changing.
+//
+// The neighbour lists are only restored when the forces asking the system for
+// them were set up before reading. Otherwise they are built anew, which gives
+// a run that can differ from the saved one in rounding.
+//
+// Sections of the checkpoint the system doesn't know are skipped. When the
+// checkpoint can't be read, the system is left as it was.
+func (sys *System) ReadCheckpoint(r io.Reader) error {
+
+	magic := make([]byte, len(checkpointMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != checkpointMagic {
+
+		return errors.New("checkpoint: not a checkpoint")
+	}
+
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+
+		return fmt.Errorf("checkpoint: %s", err)
+	}
+	if version > CheckpointVersion {
+
+		return fmt.Errorf("checkpoint: unsupported version %d", version)
+	}
+
+	extras := make(map[string]Checkpointed)
+	for _, c := range sys.extraSections() {
+
+		extras[c.Section()] = c
+	}
+
+	// Everything is decoded aside and only restored once the whole checkpoint
+	// was read, so that a corrupt one leaves the system as it was
+	restored := &System{algo: sys.algo, box: sys.box}
+	extraData := make(map[string][]byte)
+
+	var n uint64
+	haveSystem, haveBodies := false, false
+
+	for {
+
+		name, data, err := readSection(r)
+		if err != nil {
+
+			return fmt.Errorf("checkpoint: %s", err)
+		}
+
+		sec := &sectionReader{data: data}
+
+		switch name {
+
+		case "end":
+			if !haveBodies {
+
+				return errors.New("checkpoint: no bodies")
+			}
+
+			if err := restoreExtras(extras, extraData); err != nil {
+
+				return fmt.Errorf("checkpoint: %s", err)
+			}
+
+			sys.restore(restored)
+
+			return nil
+
+		case "system":
+			n, err = restored.readSystem(sec)
+			haveSystem = err == nil
+
+		case "bodies":
+			if !haveSystem {
+
+				return errors.New("checkpoint: bodies before the system")
+			}
+
+			err = restored.readBodies(sec, n)
+			haveBodies = err == nil
+
+		default:
+			if _, ok := extras[name]; ok {
+
+				extraData[name] = data
+			}
+		}
+
+		if err != nil {
+
+			return fmt.Errorf("checkpoint: section %s: %s", name, err)
+		}
+	}
+}
+
+// Restores the sections of the things saved along with the bodies. When one
+// of them fails, the ones restored so far are put back the way they were.
+func restoreExtras(extras map[string]Checkpointed, data map[string][]byte) error {
+
+	before := make(map[string][]byte)
+
+	for name, d := range data {
+
+		c := extras[name]
+
+		old, err := c.MarshalBinary()
+		if err != nil {
+
+			return fmt.Errorf("section %s: %s", name, err)
+		}
+		before[name] = old
+
+		if err := c.UnmarshalBinary(d); err != nil {
+
+			for name, old := range before {
+
+				extras[name].UnmarshalBinary(old)
+			}
+
+			return fmt.Errorf("section %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Takes over the time, step, bodies and their storage of a system read from a
+// checkpoint. The bodies obtained from sys before keep their state, detached
+// from it.
+func (sys *System) restore(from *System) {
+
+	for _, b := range sys.bodies {
+
+		b.detach()
+	}
+
+	sys.t, sys.step, sys.nextID = from.t, from.step, from.nextID
+
+	sys.xs, sys.vs, sys.as = from.xs, from.vs, from.as
+	sys.masses, sys.props = from.masses, from.props
+	sys.bodies = from.bodies
+
+	sys.bind()
+}
+
+// Reads the system section, returning the number of bodies
+func (sys *System) readSystem(sec *sectionReader) (uint64, error) {
+
+	algo := sec.str()
+	stateLen := sec.u64()
+	n := sec.u64()
+
+	t, step, nextID := sec.f64(), int(sec.i64()), int(sec.i64())
+
+	if sec.err != nil {
+
+		return 0, sec.err
+	}
+
+	if algo != integratorName(sys.algo) || stateLen != uint64(sys.algo.StateLen()) {
+
+		return 0, fmt.Errorf(
+			"saved with integrator %s, not %s", algo, integratorName(sys.algo),
+		)
+	}
+
+	sys.t, sys.step, sys.nextID = t, step, nextID
+
+	return n, nil
+}
+
+// Reads count bodies into new storage of the system
+func (sys *System) readBodies(sec *sectionReader, count uint64) error {
+
+	stateLen := sys.algo.StateLen()
+
+	// The least a body can take up, without a species and attributes
+	least := 8 + 8 + 3*8 + 3*(1+8) + stateLen*2*3*8 + 8
+	if count > uint64(len(sec.data)/least) {
+
+		return fmt.Errorf("%d bodies don't fit in %d bytes", count, len(sec.data))
+	}
+
+	n := int(count)
+
+	sys.xs = make([]vect.Vector, n*stateLen)
+	sys.vs = make([]vect.Vector, n*stateLen)
+	sys.as = make([]vect.Vector, n)
+	sys.masses = make([]float64, n)
+	sys.props = make([]properties, n)
+
+	views := make([]Body, n)
+
+	sys.bodies = make([]*Body, n)
+	for i := range sys.bodies {
+
+		views[i] = bodyIn(sys.algo, nil, nil, nil, nil)
+		views[i].box = sys.box
+
+		sys.bodies[i] = &views[i]
+	}
+
+	sys.bind()
+
+	species := make(map[string]*Species)
+
+	for _, b := range sys.bodies {
+
+		p := b.props
+
+		p.id = int(sec.i64())
+
+		if name := sec.str(); name != "" {
+
+			s := Species{Name: name, Mass: sec.f64(), Charge: sec.f64(), Radius: sec.f64()}
+
+			if species[name] == nil {
+
+				species[name] = &s
+			}
+
+			p.species = species[name]
+		}
+
+		b.SetMass(sec.f64())
+		p.charge, p.radius = sec.f64(), sec.f64()
+
+		for k := range p.frozen {
+
+			p.frozen[k] = sec.flag()
+			b.image[k] = int(sec.i64())
+		}
+
+		for k := range b.Xs {
+
+			b.Xs[k], b.Vs[k] = sec.vec(), sec.vec()
+		}
+
+		p.attrs = sec.attrs()
+	}
+
+	return sec.err
+}
+
+// Identifies the integrator together with it's parameters
+func integratorName(algo Integrator) string {
+
+	return fmt.Sprintf("%T%+v", algo, algo)
+}
+
+// The longest section name accepted
+const maxSectionName = 1 << 10
+
+// Reads the name and contents of the next section
+func readSection(r io.Reader) (name string, data []byte, err error) {
+
+	var nameLen uint32
+	if err = binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+
+		return "", nil, err
+	}
+	if nameLen > maxSectionName {
+
+		return "", nil, fmt.Errorf("section name of %d bytes", nameLen)
+	}
+
+	nameAndLen := make([]byte, nameLen+8)
+	if _, err = io.ReadFull(r, nameAndLen); err != nil {
+
+		return "", nil, err
+	}
+
+	size := binary.LittleEndian.Uint64(nameAndLen[nameLen:])
+	if size > math.MaxInt64 {
+
+		return "", nil, fmt.Errorf("section of %d bytes", size)
+	}
+
+	// The buffer only grows as far as there is data to fill it
+	var buf bytes.Buffer
+	if _, err = io.CopyN(&buf, r, int64(size)); err != nil {
+
+		return "", nil, err
+	}
+
+	return string(nameAndLen[:nameLen]), buf.Bytes(), nil
+}
+
+// Collects the contents of a checkpoint section
+type sectionWriter struct {
+	buf bytes.Buffer
+}
+
+// Writes out the collected contents as a section with the given name and
+// starts collecting anew
+func (sec *sectionWriter) to(out *bytes.Buffer, name string) {
+
+	binary.Write(out, binary.LittleEndian, uint32(len(name)))
+	out.WriteString(name)
+	binary.Write(out, binary.LittleEndian, uint64(sec.buf.Len()))
+
+	sec.buf.WriteTo(out)
+}
+
+func (sec *sectionWriter) u64(x uint64) {
+
+	binary.Write(&sec.buf, binary.LittleEndian, x)
+}
+
+func (sec *sectionWriter) i64(x int64) {
+
+	sec.u64(uint64(x))
+}
+
+func (sec *sectionWriter) f64(x float64) {
+
+	sec.u64(math.Float64bits(x))
+}
+
+func (sec *sectionWriter) flag(b bool) {
+
+	if b {
+
+		sec.buf.WriteByte(1)
+
+	} else {
+
+		sec.buf.WriteByte(0)
+	}
+}
+
+func (sec *sectionWriter) str(s string) {
+
+	sec.u64(uint64(len(s)))
+	sec.buf.WriteString(s)
+}
+
+func (sec *sectionWriter) vec(v vect.Vector) {
+
+	for _, x := range components(v) {
+
+		sec.f64(x)
+	}
+}
+
+// The kinds of attribute values saved
+const (
+	attrString byte = iota
+	attrInt
+	attrFloat
+	attrBool
+)
+
+// Writes the attributes of the kinds that can be saved
+func (sec *sectionWriter) attrs(attrs map[string]interface{}) {
+
+	var inner sectionWriter
+	count := 0
+
+	for key, value := range attrs {
+
+		switch value := value.(type) {
+
+		case string:
+			inner.str(key)
+			inner.buf.WriteByte(attrString)
+			inner.str(value)
+
+		case int:
+			inner.str(key)
+			inner.buf.WriteByte(attrInt)
+			inner.i64(int64(value))
+
+		case float64:
+			inner.str(key)
+			inner.buf.WriteByte(attrFloat)
+			inner.f64(value)
+
+		case bool:
+			inner.str(key)
+			inner.buf.WriteByte(attrBool)
+			inner.flag(value)
+
+		default:
+			continue
+		}
+
+		count++
+	}
+
+	sec.u64(uint64(count))
+	inner.buf.WriteTo(&sec.buf)
+}
+
+// Reads the contents of a checkpoint section. Once it runs out of data, it
+// keeps the error and returns zero values.
+type sectionReader struct {
+	data []byte
+	err  error
+}
+
+func (sec *sectionReader) bytes(n uint64) []byte {
+
+	if sec.err == nil && uint64(len(sec.data)) < n {
+
+		sec.err = io.ErrUnexpectedEOF
+	}
+
+	if sec.err != nil {
+
+		return make([]byte, n)
+	}
+
+	b := sec.data[:n]
+	sec.data = sec.data[n:]
+
+	return b
+}
+
+func (sec *sectionReader) u64() uint64 {
+
+	return binary.LittleEndian.Uint64(sec.bytes(8))
+}
+
+func (sec *sectionReader) i64() int64 {
+
+	return int64(sec.u64())
+}
+
+func (sec *sectionReader) f64() float64 {
+
+	return math.Float64frombits(sec.u64())
+}
+
+func (sec *sectionReader) flag() bool {
+
+	return sec.bytes(1)[0] != 0
+}
+
+func (sec *sectionReader) str() string {
+
+	n := sec.u64()
+	if n > uint64(len(sec.data)) {
+
+		sec.err = io.ErrUnexpectedEOF
+
+		return ""
+	}
+
+	return string(sec.bytes(n))
+}
+
+func (sec *sectionReader) vec() vect.Vector {
+
+	var x [3]float64
+
+	for k := range x {
+
+		x[k] = sec.f64()
+	}
+
+	return fromComponents(x)
+}
+
+func (sec *sectionReader) attrs() map[string]interface{} {
+
+	n := sec.u64()
+	if n == 0 || sec.err != nil {
+
+		return nil
+	}
+
+	attrs := make(map[string]interface{})
+
+	for i := uint64(0); i < n && sec.err == nil; i++ {
+
+		key := sec.str()
+
+		switch kind := sec.bytes(1)[0]; kind {
+
+		case attrString:
+			attrs[key] = sec.str()
+
+		case attrInt:
+			attrs[key] = int(sec.i64())
+
+		case attrFloat:
+			attrs[key] = sec.f64()
+
+		case attrBool:
+			attrs[key] = sec.flag()
+
+		default:
+			sec.err = fmt.Errorf("unknown attribute kind %d", kind)
+		}
+	}
+
+	return attrs
+}
@@ -0,0 +1,256 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRestartContinuesBitForBit(t *testing.T) {
+
+	uninterrupted, interrupted := ljGas(64, 4), ljGas(64, 4)
+
+	argon := &Species{Name: "Ar", Mass: 1, Radius: 0.5}
+	interrupted.Body(3).SetSpecies(argon)
+	interrupted.Body(3).SetAttr("tag", "probe")
+	interrupted.Body(5).SetFrozen(false, true, false)
+	uninterrupted.Body(5).SetFrozen(false, true, false)
+
+	for i := 0; i < 30; i++ {
+
+		uninterrupted.Step(0.005)
+		interrupted.Step(0.005)
+	}
+
+	var buf bytes.Buffer
+	if err := interrupted.WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	restarted := ljGas(1, 0)
+	if err := restarted.ReadCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 30; i++ {
+
+		uninterrupted.Step(0.005)
+		restarted.Step(0.005)
+	}
+
+	if restarted.StepIndex() != 60 || restarted.Time() != uninterrupted.Time() {
+
+		t.Errorf(
+			"the restarted run should be at step 60 and time %g, not %d and %g",
+			uninterrupted.Time(), restarted.StepIndex(), restarted.Time(),
+		)
+	}
+
+	for i := 0; i < uninterrupted.Bodies(); i++ {
+
+		a, b := uninterrupted.Body(i), restarted.Body(i)
+
+		for k := range a.Xs {
+
+			if a.Xs[k] != b.Xs[k] || a.Vs[k] != b.Vs[k] {
+
+				t.Fatalf("body %d differs after the restart", i)
+			}
+		}
+
+		if a.Image() != b.Image() {
+
+			t.Fatalf("body %d should be in image %v not %v", i, a.Image(), b.Image())
+		}
+	}
+
+	probe := restarted.Body(3)
+	if tag, _ := probe.Attr("tag"); probe.SpeciesName() != "Ar" || probe.Radius() != 0.5 || tag != "probe" {
+
+		t.Errorf("the properties of body 3 should be restored")
+	}
+}
+
+func TestCheckpointNeedsTheSameIntegrator(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := ljGas(8, 1).WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if err := NewSystem(Euler, 8).ReadCheckpoint(&buf); err == nil {
+
+		t.Fatal("a Verlet checkpoint should not restore into an Euler system")
+	}
+
+	if err := ljGas(8, 1).ReadCheckpoint(strings.NewReader("newtonCP")); err == nil {
+
+		t.Fatal("a truncated checkpoint should not restore")
+	}
+}
+
+func TestCorruptCheckpointsAreErrors(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := ljGas(2, 1).WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	header := valid[:len(checkpointMagic)+4]
+
+	var sec sectionWriter
+	var hugeBodies bytes.Buffer
+	hugeBodies.Write(header)
+	sec.str(integratorName(Verlet))
+	sec.u64(uint64(Verlet.StateLen()))
+	sec.u64(1 << 62)
+	sec.f64(0)
+	sec.i64(0)
+	sec.i64(0)
+	sec.to(&hugeBodies, "system")
+	sec.u64(0)
+	sec.to(&hugeBodies, "bodies")
+	sec.to(&hugeBodies, "end")
+
+	corrupt := map[string][]byte{
+		"a huge section name": append(append([]byte(nil), header...), 0xFF, 0xFF, 0xFF, 0xFF),
+		"a huge body count":   hugeBodies.Bytes(),
+	}
+
+	for i := range valid {
+
+		corrupt[fmt.Sprintf("cut at byte %d", i)] = valid[:i]
+
+		flipped := append([]byte(nil), valid...)
+		flipped[i] ^= 0xFF
+		corrupt[fmt.Sprintf("flipped byte %d", i)] = flipped
+	}
+
+	for name, data := range corrupt {
+
+		func() {
+
+			defer func() {
+
+				if p := recover(); p != nil {
+
+					t.Errorf("reading a checkpoint with %s should not panic: %v", name, p)
+				}
+			}()
+
+			err := ljGas(2, 1).ReadCheckpoint(bytes.NewReader(data))
+
+			if err == nil && strings.HasPrefix(name, "a huge") {
+
+				t.Errorf("reading a checkpoint with %s should fail", name)
+			}
+		}()
+	}
+}
+
+func TestFailedRestoreLeavesTheSystem(t *testing.T) {
+
+	saved := ljGas(8, 1)
+	for i := 0; i < 10; i++ {
+
+		saved.Step(0.005)
+	}
+
+	var buf bytes.Buffer
+	if err := saved.WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	// Everything but the end section
+	cut := buf.Bytes()[:buf.Len()-len("end")-12]
+
+	sys := ljGas(27, 2)
+	sys.RNG().Seed(42)
+	x := sys.Body(0).XNow()
+	state := sys.RNG().Stream(0, 0).NormFloat64()
+
+	if err := sys.ReadCheckpoint(bytes.NewReader(cut)); err == nil {
+
+		t.Fatal("a checkpoint without an end should not restore")
+	}
+
+	if sys.Bodies() != 27 || sys.StepIndex() != 0 || sys.Time() != 0 || sys.Body(0).XNow() != x {
+
+		t.Errorf("the bodies, step and time should stay as they were")
+	}
+
+	if sys.RNG().Stream(0, 0).NormFloat64() != state {
+
+		t.Errorf("the random number generator should stay as it was")
+	}
+}
+
+func TestRestartWithNeighbourListsContinuesBitForBit(t *testing.T) {
+
+	// A gas whose force looks up neighbours in a list of the system
+	listed := func(n int) *System {
+
+		sys := ljGas(n, 4)
+		sys.SetSkin(0.5)
+		sys.SetForce(&PairForce{
+			Potential:  Shifted(LennardJones{Epsilon: 1, Sigma: 1}, 2.5),
+			Cutoff:     2.5,
+			Neighbours: sys.NeighbourList(2.5),
+		})
+
+		return sys
+	}
+
+	uninterrupted, interrupted := listed(64), listed(64)
+
+	for i := 0; i < 7; i++ {
+
+		uninterrupted.Step(0.005)
+		interrupted.Step(0.005)
+	}
+
+	var buf bytes.Buffer
+	if err := interrupted.WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	restarted := listed(1)
+	if err := restarted.ReadCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+
+		uninterrupted.Step(0.005)
+		restarted.Step(0.005)
+	}
+
+	if restarted.NeighbourRebuilds() != uninterrupted.NeighbourRebuilds() {
+
+		t.Errorf(
+			"the list should be rebuilt %d times, not %d",
+			uninterrupted.NeighbourRebuilds(), restarted.NeighbourRebuilds(),
+		)
+	}
+
+	for i := 0; i < uninterrupted.Bodies(); i++ {
+
+		if uninterrupted.Body(i).XLatest() != restarted.Body(i).XLatest() {
+
+			t.Fatalf("body %d differs after the restart", i)
+		}
+	}
+}
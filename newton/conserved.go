@@ -0,0 +1,277 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"log"
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// The total kinetic energy of the bodies, at their latest velocities, so
+// that it matches the potential energy
+func (sys *System) KineticEnergy() float64 {
+
+	e := 0.0
+
+	for _, b := range sys.bodies {
+
+		v := b.VLatest()
+
+		e += b.Mass() * v.Dot(v) / 2
+	}
+
+	return e * sys.mvv2e()
+}
+
+// The potential energy of the system force at the latest positions. Forces
+// that are not Potentials do not contribute. The force is not prepared, so
+// observers can call this without disturbing it.
+func (sys *System) PotentialEnergy() float64 {
+
+	p, ok := sys.force.(Potential)
+	if !ok {
+
+		return 0
+	}
+
+	return p.Energy(sys.bodies)
+}
+
+// The total momentum of the bodies, at their latest velocities
+func (sys *System) Momentum() vect.Vector {
+
+	p := vect.Zero
+
+	for _, b := range sys.bodies {
+
+		p = p.Plus(b.VLatest().Scale(b.Mass()))
+	}
+
+	return p
+}
+
+// The centre of mass of the bodies at their latest positions, as if they
+// never got wrapped back into the box
+func (sys *System) CenterOfMass() vect.Vector {
+
+	x, m := vect.Zero, 0.0
+
+	for _, b := range sys.bodies {
+
+		x = x.Plus(b.xLatestUnwrapped().Scale(b.Mass()))
+		m += b.Mass()
+	}
+
+	if m == 0 {
+
+		return vect.Zero
+	}
+
+	return x.Scale(1 / m)
+}
+
+// The angular momentum of the bodies about their centre of mass, at their
+// latest states
+func (sys *System) AngularMomentum() vect.Vector {
+
+	com := sys.CenterOfMass()
+
+	l := vect.Zero
+
+	for _, b := range sys.bodies {
+
+		r := b.xLatestUnwrapped().Minus(com)
+
+		l = l.Plus(r.Cross(b.VLatest()).Scale(b.Mass()))
+	}
+
+	return l
+}
+
+// Remove the motion of the centre of mass every given number of steps. Zero
+// turns the removal off.
+func (sys *System) SetDriftRemoval(every int) {
+
+	sys.driftEvery = every
+}
+
+// Remove the velocity of the centre of mass from all the bodies, along each
+// axis among the bodies free to move along it
+//
+// The whole histories are changed, as if the bodies had been moving without
+// the drift in steps of dt.
+func (sys *System) RemoveDrift(dt float64) {
+
+	vs := make([]vect.Vector, len(sys.bodies))
+
+	for i, b := range sys.bodies {
+
+		vs[i] = b.VLatest()
+	}
+
+	sys.removeDrift(vs)
+
+	for i, b := range sys.bodies {
+
+		drift := b.VLatest().Minus(vs[i])
+
+		for k := range b.Xs {
+
+			b.Xs[k] = b.Xs[k].Plus(drift.Scale(float64(k) * dt))
+			b.Vs[k] = b.Vs[k].Minus(drift)
+		}
+	}
+}
+
+// The conserved quantities at one step
+type DriftRecord struct {
+	Step            int
+	Time            float64
+	Energy          float64
+	Momentum        vect.Vector
+	AngularMomentum vect.Vector
+}
+
+// The number of the latest records a DriftMonitor keeps by default
+const DefaultDriftWindow = 1000
+
+// An observer recording the total energy, momentum and angular momentum of a
+// system, that warns when they drift away from their first recorded values
+//
+// Each kind of drift is warned about once, when it first exceeds the
+// tolerance. A zero tolerance turns the warning off. The angular momentum is
+// only checked in open space.
+//
+// Only the first record and a window of the latest ones are kept, so a long
+// run doesn't fill the memory with them.
+type DriftMonitor struct {
+	// The largest change in energy relative to the first recorded one. When
+	// the first total energy is smaller than the first kinetic energy, it is
+	// relative to that instead, and when both are zero the change is absolute.
+	EnergyTolerance float64
+	// The largest change in the length of the momentum and angular momentum
+	MomentumTolerance float64
+	// Called with the warnings. They go to the standard logger when it is nil.
+	Warn func(format string, args ...interface{})
+	// The number of the latest records kept. Zero means DefaultDriftWindow.
+	Window int
+
+	first   DriftRecord
+	kinetic float64
+	// The latest records, which can be up to twice the window before the
+	// older ones are dropped, and the number of all the records made
+	latest []DriftRecord
+	count  int
+	warned [3]bool
+}
+
+// Constructs a drift monitor with the given tolerances
+func NewDriftMonitor(energyTolerance, momentumTolerance float64) *DriftMonitor {
+
+	return &DriftMonitor{
+		EnergyTolerance:   energyTolerance,
+		MomentumTolerance: momentumTolerance,
+	}
+}
+
+// The first record and the latest ones kept, oldest first
+func (dm *DriftMonitor) Records() []DriftRecord {
+
+	recs := dm.latest
+	if over := len(recs) - dm.window(); over > 0 {
+
+		recs = recs[over:]
+	}
+
+	if len(recs) < dm.count {
+
+		return append([]DriftRecord{dm.first}, recs...)
+	}
+
+	return recs
+}
+
+// The number of the latest records kept
+func (dm *DriftMonitor) window() int {
+
+	if dm.Window <= 0 {
+
+		return DefaultDriftWindow
+	}
+
+	return dm.Window
+}
+
+func (dm *DriftMonitor) Observe(v View) {
+
+	kinetic := v.KineticEnergy()
+
+	rec := DriftRecord{
+		Step:            v.StepIndex(),
+		Time:            v.Time(),
+		Energy:          kinetic + v.PotentialEnergy(),
+		Momentum:        v.Momentum(),
+		AngularMomentum: v.AngularMomentum(),
+	}
+
+	if dm.count == 0 {
+
+		dm.first, dm.kinetic = rec, kinetic
+	}
+
+	dm.remember(rec)
+
+	first := dm.first
+
+	energy := math.Abs(rec.Energy - first.Energy)
+	if scale := math.Max(math.Abs(first.Energy), dm.kinetic); scale > 0 {
+
+		energy /= scale
+	}
+
+	dm.check(0, energy, dm.EnergyTolerance, "energy", rec)
+	dm.check(1, rec.Momentum.Minus(first.Momentum).Norm(), dm.MomentumTolerance, "momentum", rec)
+
+	// Periodic boundaries do not conserve angular momentum
+	if v.Box() == Open {
+
+		dm.check(2, rec.AngularMomentum.Minus(first.AngularMomentum).Norm(), dm.MomentumTolerance, "angular momentum", rec)
+	}
+}
+
+// Keep the record among the latest ones. The ones beyond the window are
+// dropped once they take up as much again, so that it is done rarely.
+func (dm *DriftMonitor) remember(rec DriftRecord) {
+
+	dm.latest = append(dm.latest, rec)
+	dm.count++
+
+	if window := dm.window(); len(dm.latest) > 2*window {
+
+		dm.latest = append([]DriftRecord(nil), dm.latest[len(dm.latest)-window:]...)
+	}
+}
+
+// Warn about the drift of a quantity, unless it is within the tolerance or was
+// warned about before
+func (dm *DriftMonitor) check(which int, drift, tolerance float64, name string, rec DriftRecord) {
+
+	if tolerance == 0 || drift <= tolerance || dm.warned[which] {
+
+		return
+	}
+
+	dm.warned[which] = true
+
+	warn := dm.Warn
+	if warn == nil {
+
+		warn = log.Printf
+	}
+
+	warn("newton: %s drifted by %g at step %d (t = %g)", name, drift, rec.Step, rec.Time)
+}
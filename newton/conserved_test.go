@@ -0,0 +1,179 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestConservedQuantities(t *testing.T) {
+
+	// Two bodies rotating about their centre of mass at (1, 0, 0)
+	sys := NewSystem(Verlet, 0)
+	sys.AddBody(vect.Zero, vect.UnitY.Negate(), 1, 0.1)
+	sys.AddBody(vect.UnitX.Scale(2), vect.UnitY, 1, 0.1)
+
+	if com := sys.CenterOfMass(); !near(com, vect.UnitX) {
+
+		t.Errorf("the centre of mass should be at %v not %v", vect.UnitX, com)
+	}
+
+	if p := sys.Momentum(); !near(p, vect.Zero) {
+
+		t.Errorf("the momentum should be zero not %v", p)
+	}
+
+	if l := sys.AngularMomentum(); !near(l, vect.UnitZ.Scale(2)) {
+
+		t.Errorf("the angular momentum should be %v not %v", vect.UnitZ.Scale(2), l)
+	}
+
+	if e := sys.KineticEnergy(); e != 1 {
+
+		t.Errorf("the kinetic energy should be 1 not %g", e)
+	}
+}
+
+func TestRemoveDrift(t *testing.T) {
+
+	sys := ljGas(27, 3)
+	for i := 0; i < sys.Bodies(); i++ {
+
+		b := sys.Body(i)
+		b.moveUniformly(b.XLatest(), b.VLatest().Plus(vect.UnitX), 0.005)
+	}
+
+	sys.SetDriftRemoval(10)
+
+	if err := sys.Run(context.Background(), 0.005, 10); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if p := sys.Momentum(); p.Norm() > 1e-9 {
+
+		t.Fatalf("the momentum should be removed, not be %v", p)
+	}
+
+	// The momentum stays removed in the following steps, so the velocities
+	// implied by the histories were changed as well
+	if err := sys.Run(context.Background(), 0.005, 5); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if p := sys.Momentum(); p.Norm() > 1e-6 {
+
+		t.Errorf("the momentum should stay zero, not become %v", p)
+	}
+}
+
+func TestDriftMonitorWarns(t *testing.T) {
+
+	for _, c := range []struct {
+		dt   float64
+		warn bool
+	}{{0.005, false}, {0.05, true}} {
+
+		sys := ljGas(27, 2)
+
+		xs, vs := make([]vect.Vector, sys.Bodies()), make([]vect.Vector, sys.Bodies())
+		for i := range xs {
+
+			xs[i], vs[i] = sys.Body(i).Latest()
+		}
+		sys.Initialize(xs, vs, c.dt)
+
+		var warnings []string
+
+		dm := NewDriftMonitor(1e-2, 1e-6)
+		dm.Warn = func(format string, args ...interface{}) {
+
+			warnings = append(warnings, fmt.Sprintf(format, args...))
+		}
+		sys.AddObserver(10, dm)
+
+		if err := sys.Run(context.Background(), c.dt, 200); err != nil {
+
+			t.Fatal(err)
+		}
+
+		if len(dm.Records()) != 21 {
+
+			t.Errorf("the monitor should record 21 steps, not %d", len(dm.Records()))
+		}
+
+		if warned := len(warnings) > 0; warned != c.warn {
+
+			t.Errorf("with dt = %g there should be warnings: %v, got %q", c.dt, c.warn, warnings)
+		}
+	}
+}
+
+func TestDriftMonitorKeepsAWindow(t *testing.T) {
+
+	sys := ljGas(8, 1)
+
+	dm := NewDriftMonitor(0, 0)
+	dm.Window = 5
+	sys.AddObserver(1, dm)
+
+	if err := sys.Run(context.Background(), 0.005, 30); err != nil {
+
+		t.Fatal(err)
+	}
+
+	var steps []int
+	for _, rec := range dm.Records() {
+
+		steps = append(steps, rec.Step)
+	}
+
+	if want := []int{0, 26, 27, 28, 29, 30}; fmt.Sprint(steps) != fmt.Sprint(want) {
+
+		t.Errorf("the records should be for steps %v, not %v", want, steps)
+	}
+}
+
+func TestDriftMonitorWithNoTotalEnergy(t *testing.T) {
+
+	lj := LennardJones{Epsilon: 1, Sigma: 1}
+	u := lj.Energy(1.2)
+
+	// Two bodies flying apart with a kinetic energy cancelling the potential
+	sys := NewSystem(Verlet, 2)
+	sys.SetForce(&PairForce{Potential: lj, Cutoff: 5})
+
+	sys.Body(0).SetMass(1)
+	sys.Body(1).SetMass(1)
+
+	v := vect.UnitX.Scale(math.Sqrt(-u))
+	sys.Initialize(
+		[]vect.Vector{vect.Zero, vect.UnitX.Scale(1.2)},
+		[]vect.Vector{v.Negate(), v},
+		0.001,
+	)
+
+	warned := false
+
+	dm := NewDriftMonitor(1e-3, 0)
+	dm.Warn = func(format string, args ...interface{}) { warned = true }
+	sys.AddObserver(10, dm)
+
+	if err := sys.Run(context.Background(), 0.001, 200); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if warned {
+
+		t.Errorf("the energy should not drift relative to the kinetic energy of %g", -u)
+	}
+}
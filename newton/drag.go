@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A drag proportional to the velocity of each body
+//
+//	F = -Gamma v
+type LinearDrag struct {
+	Gamma float64
+}
+
+func (ld LinearDrag) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	b := bs[i]
+
+	return b.VLatest().Scale(-ld.Gamma / b.Mass())
+}
+
+// A drag proportional to the square of each body's speed
+//
+//	F = -C |v| v
+type QuadraticDrag struct {
+	C float64
+}
+
+func (qd QuadraticDrag) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	b := bs[i]
+
+	v := b.VLatest()
+
+	return v.Scale(-qd.C * v.Norm() / b.Mass())
+}
+
+// The magnetic part of the Lorentz force for bodies carrying a charge Q in a
+// uniform field B
+//
+//	F = Q v x B
+type Lorentz struct {
+	Q float64
+	B vect.Vector
+}
+
+func (l Lorentz) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	b := bs[i]
+
+	return b.VLatest().Cross(l.B).Scale(l.Q / b.Mass())
+}
+
+// The force a dashpot with damping coefficient c along dir exerts on the i-th
+// body, when it's other end is attached to the j-th one
+func dashpot(bs []*Body, i, j int, dir vect.Vector, c float64) vect.Vector {
+
+	dv := bs[j].VLatest().Minus(bs[i].VLatest())
+
+	return dir.Scale(c * dv.Dot(dir))
+}
@@ -0,0 +1,141 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestDrag(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	v := vect.NewVector(1, 2, -2)
+	sys.AddBody(vect.Zero, v, 2, 0.1)
+
+	drags := []struct {
+		name  string
+		force Force
+		want  vect.Vector
+	}{
+		{"linear", LinearDrag{Gamma: 0.5}, v.Scale(-0.5 / 2)},
+		{"quadratic", QuadraticDrag{C: 0.5}, v.Scale(-0.5 * 3 / 2)},
+		{"Lorentz", Lorentz{Q: 2, B: vect.UnitZ}, v.Cross(vect.UnitZ)},
+	}
+
+	for _, d := range drags {
+
+		if a := d.force.Accel(sys.bodies, 0, 0.1); !near(a, d.want) {
+
+			t.Errorf("the %s drag should accelerate the body by %v not %v", d.name, d.want, a)
+		}
+	}
+}
+
+func TestLorentzPreservesSpeed(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	b := sys.AddBody(vect.Zero, vect.NewVector(1, 0, 0.5), 1, 0.001)
+	sys.SetForce(Lorentz{Q: 1, B: vect.UnitZ})
+
+	speed := b.VLatest().Norm()
+
+	// About a turn and a half
+	for i := 0; i < 10000; i++ {
+
+		sys.Step(0.001)
+
+		if s := b.VNow().Norm(); math.Abs(s-speed) > 1e-5*speed {
+
+			t.Fatalf("the speed should stay %g, not become %g at step %d", speed, s, i)
+		}
+	}
+}
+
+// The positive peaks of the x coordinate of the second body, pulled back to
+// the first one by the force, and the times they happen at
+func dampedPeaks(f Force, x0, dt float64, steps int) (xs, ts []float64) {
+
+	sys := NewSystem(Verlet, 0)
+	sys.AddBody(vect.Zero, vect.Zero, 1, dt).Freeze()
+	b := sys.AddBody(vect.NewVector(x0, 0, 0), vect.Zero, 1, dt)
+	sys.SetForce(f)
+
+	for i := 0; i < steps; i++ {
+
+		before := b.XNow().Dot(vect.UnitX)
+
+		sys.Step(dt)
+
+		// The current position is a step behind the latest one
+		if x := b.XNow().Dot(vect.UnitX); x > before && x > b.XLatest().Dot(vect.UnitX) {
+
+			xs, ts = append(xs, x), append(ts, sys.Time()-dt)
+		}
+	}
+
+	return xs, ts
+}
+
+func TestDampedSpringsDecay(t *testing.T) {
+
+	const k, c, l0 = 1.0, 0.2, 1.0
+
+	springs := [][]Spring{
+		{{}, {K: k, L0: l0, Damping: c}},
+		{{K: k, L0: l0, Damping: c}, {}},
+	}
+	bonds := Bonds{{I: 1, J: 0, Potential: Harmonic{K: k, R0: l0}, Damping: c}}
+
+	for name, f := range map[string]Force{"spring": &Hooke{Springs: springs}, "bond": &bonds} {
+
+		xs, ts := dampedPeaks(f, l0+0.1, 0.001, 20000)
+
+		if len(xs) < 2 {
+
+			t.Fatalf("%s: the body should oscillate, but only peaked at %v", name, xs)
+		}
+
+		// With a unit mass the amplitude falls as exp(-c t / 2)
+		last := len(xs) - 1
+		got := (xs[last] - l0) / (xs[0] - l0)
+		want := math.Exp(-c * (ts[last] - ts[0]) / 2)
+
+		if !closeTo(got, want, 1e-3) {
+
+			t.Errorf("%s: the amplitude should fall by %g not %g", name, want, got)
+		}
+	}
+}
+
+func TestVerletLatestVelocityIsSecondOrder(t *testing.T) {
+
+	// The error in the latest velocity of a harmonic oscillator at t = 1
+	velocityError := func(dt float64) float64 {
+
+		sys := NewSystem(Verlet, 1)
+		sys.Body(0).SetMass(1)
+		sys.SetForce(Restraints{{I: 0, K: 1}})
+		sys.Initialize([]vect.Vector{vect.UnitX}, []vect.Vector{vect.Zero}, dt)
+
+		for i := 0; i < int(math.Round(1/dt)); i++ {
+
+			sys.Step(dt)
+		}
+
+		v := sys.Body(0).VLatest()
+
+		return v.Minus(vect.UnitX.Scale(-math.Sin(sys.Time()))).Norm()
+	}
+
+	coarse, fine := velocityError(0.01), velocityError(0.005)
+
+	if ratio := coarse / fine; ratio < 3.5 || ratio > 4.5 {
+
+		t.Errorf("halving the step should quarter the velocity error, not divide it by %g", ratio)
+	}
+}
@@ -0,0 +1,101 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+	"math"
+)
+
+// A scalar function of time
+type Signal interface {
+	At(t float64) float64
+}
+
+// A sinusoidal signal
+//
+//	sin(Omega t + Phase)
+type Sine struct {
+	Omega, Phase float64
+}
+
+func (s Sine) At(t float64) float64 {
+
+	return math.Sin(s.Omega*t + s.Phase)
+}
+
+// A signal growing linearly from 0 to 1 during Duration after Start and
+// staying at 1 afterwards
+type Ramp struct {
+	Start, Duration float64
+}
+
+func (r Ramp) At(t float64) float64 {
+
+	switch {
+	case t <= r.Start:
+		return 0
+	case t >= r.Start+r.Duration:
+		return 1
+	}
+
+	return (t - r.Start) / r.Duration
+}
+
+// A rectangular pulse -- 1 for Width after Start and 0 at all other times
+type Pulse struct {
+	Start, Width float64
+}
+
+func (p Pulse) At(t float64) float64 {
+
+	if p.Start <= t && t < p.Start+p.Width {
+
+		return 1
+	}
+
+	return 0
+}
+
+// A gaussian pulse of unit height centered at Center
+type GaussianPulse struct {
+	Center, Width float64
+}
+
+func (g GaussianPulse) At(t float64) float64 {
+
+	return math.Exp(-math.Pow((t-g.Center)/g.Width, 2) / 2)
+}
+
+// A product of signals, eg. a ramped up sine
+type Modulated []Signal
+
+func (m Modulated) At(t float64) float64 {
+
+	product := 1.0
+
+	for _, s := range m {
+
+		product *= s.At(t)
+	}
+
+	return product
+}
+
+// A spatially uniform external force, that varies in time like a signal
+//
+// The time is read from the clock -- usually the System the force acts in.
+type DrivenForce struct {
+	Clock  Clock
+	Force  vect.Vector
+	Signal Signal
+}
+
+func (df DrivenForce) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	s := df.Signal.At(df.Clock.Time())
+
+	return df.Force.Scale(s / bs[i].Mass())
+}
@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestSignals(t *testing.T) {
+
+	values := []struct {
+		name   string
+		signal Signal
+		t      float64
+		want   float64
+	}{
+		{"sine at zero", Sine{Omega: 2}, 0, 0},
+		{"sine at a quarter period", Sine{Omega: 2}, math.Pi / 4, 1},
+		{"sine with a phase", Sine{Omega: 2, Phase: math.Pi / 2}, 0, 1},
+		{"ramp before start", Ramp{Start: 1, Duration: 2}, 0.5, 0},
+		{"ramp halfway", Ramp{Start: 1, Duration: 2}, 2, 0.5},
+		{"ramp after end", Ramp{Start: 1, Duration: 2}, 5, 1},
+		{"pulse before start", Pulse{Start: 1, Width: 2}, 0.5, 0},
+		{"pulse at start", Pulse{Start: 1, Width: 2}, 1, 1},
+		{"pulse at end", Pulse{Start: 1, Width: 2}, 3, 0},
+		{"gaussian at centre", GaussianPulse{Center: 1, Width: 2}, 1, 1},
+		{"gaussian a width away", GaussianPulse{Center: 1, Width: 2}, 3, math.Exp(-0.5)},
+		{"ramped sine", Modulated{Ramp{Duration: 2}, Sine{Omega: 1}}, math.Pi / 2, math.Pi / 4},
+		{"empty modulation", Modulated{}, 7, 1},
+	}
+
+	for _, v := range values {
+
+		if got := v.signal.At(v.t); math.Abs(got-v.want) > 1e-12 {
+
+			t.Errorf("the %s should be %g not %g", v.name, v.want, got)
+		}
+	}
+}
+
+func TestStepAdvancesTime(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	sys.AddBody(vect.Zero, vect.Zero, 1, 0.25)
+	sys.SetTime(0.5)
+
+	sys.Step(0.25)
+	sys.Step(0.25)
+
+	if sys.Time() != 1 {
+
+		t.Errorf("the time should be 1, not %g", sys.Time())
+	}
+}
+
+func TestDrivenForceFollowsSystemTime(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	sys.AddBody(vect.Zero, vect.Zero, 2, 0.25)
+
+	f := DrivenForce{Clock: sys, Force: vect.NewVector(4, 0, 0), Signal: Ramp{Duration: 2}}
+
+	for _, at := range []float64{0, 0.5, 1, 3} {
+
+		sys.SetTime(at)
+
+		want := vect.NewVector(2*math.Min(at/2, 1), 0, 0)
+
+		if a := f.Accel(sys.bodies, 0, 0.25); !near(a, want) {
+
+			t.Errorf("at %g the body should accelerate by %v not %v", at, want, a)
+		}
+	}
+}
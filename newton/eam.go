@@ -0,0 +1,214 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// An embedded-atom method potential for metals
+//
+// Each body is embedded in the electron density produced by it's neighbours
+// and interacts with them through a pair potential:
+//
+//	E_i = F_a(rho_i) + 1/2 sum_j phi_ab(r_ij),  rho_i = sum_j f_b(r_ij)
+//
+// where a and b are the elements of the i-th and j-th body. Since the forces
+// depend on the densities around all the bodies, these are computed in
+// Prepare, once per step. Accel panics unless the potential was prepared since
+// the bodies last moved.
+type EAM struct {
+	// The names of the elements the potential describes
+	Elements []string
+	// The masses of the elements, as given in the potential file
+	Masses []float64
+	// The distance beyond which bodies do not interact
+	Cutoff float64
+	// The element of each body, as an index into Elements. When nil, all the
+	// bodies are of the first element.
+	Types []int
+	// When not nil, neighbours are looked up in it instead of a cell list
+	Neighbours *NeighbourList
+
+	embedding []table
+	density   []table
+	// r phi(r) for each pair of elements
+	pair [][]table
+
+	nbs         [][]int
+	rho, dEmbed []float64
+	// The positions the densities were found at
+	at []vect.Vector
+}
+
+// Find the neighbours of each body, the electron densities around them and
+// the derivatives of their embedding energies
+func (eam *EAM) Prepare(bs []*Body) {
+
+	eam.nbs = neighboursFor(eam.Neighbours, bs, eam.Cutoff)
+	eam.rho = eam.densities(bs, eam.nbs)
+	eam.dEmbed = eam.embeddingSlopes(eam.rho)
+	eam.at = positionsOf(bs)
+}
+
+func (eam *EAM) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	mustBePrepared("eam", eam.at, bs, i)
+
+	f := vect.Zero
+
+	for _, j := range eam.nbs[i] {
+
+		dir, r := separation(bs, i, j).UnitAndNorm()
+		if r >= eam.Cutoff {
+			continue
+		}
+
+		f = f.Plus(dir.Scale(eam.dEdr(eam.dEmbed, i, j, r)))
+	}
+
+	return f.Scale(1 / bs[i].Mass())
+}
+
+// The derivative of the energy with respect to the separation r of the i-th
+// and j-th bodies, given the derivatives of the embedding energies
+func (eam *EAM) dEdr(dEmbed []float64, i, j int, r float64) float64 {
+
+	ti, tj := eam.typeOf(i), eam.typeOf(j)
+
+	_, dfi := eam.density[ti].at(r)
+	_, dfj := eam.density[tj].at(r)
+	_, dphi := eam.phi(ti, tj, r)
+
+	return dEmbed[i]*dfj + dEmbed[j]*dfi + dphi
+}
+
+// The virial of all the interactions. Since the forces between the bodies
+// are central, it is a sum over pairs.
+func (eam *EAM) Virial(bs []*Body) Tensor {
+
+	var w Tensor
+
+	nbs := neighbours(bs, eam.Cutoff)
+	dEmbed := eam.embeddingSlopes(eam.densities(bs, nbs))
+
+	for i := range bs {
+		for _, j := range nbs[i] {
+
+			if j < i {
+				continue
+			}
+
+			d := separation(bs, i, j)
+			if r := d.Norm(); r < eam.Cutoff {
+
+				w = w.Plus(pairVirial(d, eam.dEdr(dEmbed, i, j, r)))
+			}
+		}
+	}
+
+	return w
+}
+
+// The total embedding and pair energy of the bodies
+func (eam *EAM) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	nbs := neighbours(bs, eam.Cutoff)
+
+	for i, rho := range eam.densities(bs, nbs) {
+
+		embed, _ := eam.embedding[eam.typeOf(i)].at(rho)
+
+		u += embed
+
+		for _, j := range nbs[i] {
+
+			if j < i {
+				continue
+			}
+
+			r := separation(bs, i, j).Norm()
+			phi, _ := eam.phi(eam.typeOf(i), eam.typeOf(j), r)
+
+			u += phi
+		}
+	}
+
+	return u
+}
+
+// The electron density around each of the bodies with the given neighbours
+func (eam *EAM) densities(bs []*Body, nbs [][]int) []float64 {
+
+	rho := make([]float64, len(bs))
+
+	for i := range bs {
+		for _, j := range nbs[i] {
+
+			if j < i {
+				continue
+			}
+
+			r := separation(bs, i, j).Norm()
+			if r >= eam.Cutoff {
+				continue
+			}
+
+			fi, _ := eam.density[eam.typeOf(i)].at(r)
+			fj, _ := eam.density[eam.typeOf(j)].at(r)
+
+			rho[i] += fj
+			rho[j] += fi
+		}
+	}
+
+	return rho
+}
+
+// The derivatives of the embedding energies at the densities rho
+func (eam *EAM) embeddingSlopes(rho []float64) []float64 {
+
+	dEmbed := make([]float64, len(rho))
+
+	for i := range rho {
+
+		_, dEmbed[i] = eam.embedding[eam.typeOf(i)].at(rho[i])
+	}
+
+	return dEmbed
+}
+
+// The pair potential between elements a and b and it's derivative
+func (eam *EAM) phi(a, b int, r float64) (phi, dphi float64) {
+
+	rphi, drphi := eam.pair[a][b].at(r)
+
+	phi = rphi / r
+
+	return phi, (drphi - phi) / r
+}
+
+// The element of the i-th body
+func (eam *EAM) typeOf(i int) int {
+
+	return elementOf(eam.Types, i)
+}
+
+// Follows a body being added. When Types is set, the new body is of the first
+// element.
+func (eam *EAM) BodyAdded(i int) {
+
+	eam.Types = insertAt(eam.Types, i)
+	eam.at = nil
+}
+
+// Follows a body being removed
+func (eam *EAM) BodyRemoved(i int) {
+
+	eam.Types = removeAt(eam.Types, i)
+	eam.at = nil
+}
@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+// Bodies of unit mass at the given positions
+func bodiesAt(xs ...vect.Vector) []*Body {
+
+	bs := make([]*Body, len(xs))
+
+	for i, x := range xs {
+
+		bs[i] = NewBody(Euler)
+		bs[i].SetMass(1)
+		bs[i].SetNow(x, vect.Zero)
+	}
+
+	return bs
+}
+
+// Checks that the forces are minus the gradient of the energy
+func forceMatchesEnergy(p Potential, bs []*Body, t *testing.T) {
+
+	h := 1e-6
+
+	prepare(p, bs)
+
+	for i, b := range bs {
+
+		f := p.Accel(bs, i, 0).Scale(b.Mass())
+
+		for _, axis := range []vect.Vector{vect.UnitX, vect.UnitY, vect.UnitZ} {
+
+			x := b.Xs[0]
+
+			b.Xs[0] = x.Plus(axis.Scale(h))
+			uPlus := p.Energy(bs)
+			b.Xs[0] = x.Minus(axis.Scale(h))
+			uMinus := p.Energy(bs)
+			b.Xs[0] = x
+
+			numeric := -(uPlus - uMinus) / (2 * h)
+
+			if math.Abs(numeric-f.Dot(axis)) > 1e-4*math.Max(1, math.Abs(numeric)) {
+
+				t.Errorf(
+					"force on body %d along %v should be %f not %f",
+					i, axis, numeric, f.Dot(axis),
+				)
+			}
+		}
+	}
+}
+
+// Checks that the virial of bodies in an open box is sum_i x_i (x) F_i
+func virialMatchesForces(f Virial, bs []*Body, t *testing.T) {
+
+	prepare(f, bs)
+
+	var want Tensor
+
+	for i, b := range bs {
+
+		want = want.Plus(Outer(b.Xs[0], f.Accel(bs, i, 0).Scale(b.Mass())))
+	}
+
+	got := f.Virial(bs)
+
+	for a := range want {
+		for b := range want[a] {
+
+			if math.Abs(got[a][b]-want[a][b]) > 1e-9*math.Max(1, math.Abs(want[a][b])) {
+
+				t.Errorf("virial should be %v not %v", want, got)
+				return
+			}
+		}
+	}
+}
+
+func tabulate(buf *bytes.Buffer, n int, step float64, f func(x float64) float64) {
+
+	for i := 0; i < n; i++ {
+
+		fmt.Fprintf(buf, "%.12e\n", f(float64(i)*step))
+	}
+}
+
+// A two element setfl file with smooth analytic functions
+func testSetfl() string {
+
+	var buf bytes.Buffer
+
+	nRho, dRho, nR, dR, rc := 2000, 0.01, 2000, 0.002, 3.5
+
+	buf.WriteString("test\npotential\nfile\n")
+	fmt.Fprintf(&buf, "2 A B\n%d %g %d %g %g\n", nRho, dRho, nR, dR, rc)
+
+	for e, scale := range []float64{1, 1.5} {
+
+		fmt.Fprintf(&buf, "%d %g 3.6 fcc\n", e+1, 60*scale)
+
+		tabulate(&buf, nRho, dRho, func(rho float64) float64 {
+			return -scale * math.Sqrt(rho)
+		})
+		tabulate(&buf, nR, dR, func(r float64) float64 {
+			return scale * math.Pow(rc-r, 4) * math.Exp(-r)
+		})
+	}
+
+	for _, scale := range []float64{1, 1.2, 0.8} {
+
+		tabulate(&buf, nR, dR, func(r float64) float64 {
+			return scale * r * math.Pow(rc-r, 3) * (math.Exp(-2*r) - 0.1)
+		})
+	}
+
+	return buf.String()
+}
+
+func TestEAMForcesMatchEnergy(t *testing.T) {
+
+	eam, err := ReadSetfl(strings.NewReader(testSetfl()))
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	if len(eam.Elements) != 2 || eam.Elements[1] != "B" || eam.Masses[1] != 90 {
+
+		t.Fatalf("unexpected elements %v with masses %v", eam.Elements, eam.Masses)
+	}
+
+	bs := bodiesAt(
+		vect.NewVector(0, 0, 0),
+		vect.NewVector(1.9, 0.2, 0),
+		vect.NewVector(0.3, 2.1, -0.1),
+		vect.NewVector(1.2, 1.1, 1.6),
+		vect.NewVector(5, 5, 5),
+	)
+	eam.Types = []int{0, 1, 0, 1, 0}
+
+	forceMatchesEnergy(eam, bs, t)
+}
+
+func TestReadSetflFailsOnTruncatedFile(t *testing.T) {
+
+	file := testSetfl()
+
+	_, err := ReadSetfl(strings.NewReader(file[:len(file)/2]))
+	if err == nil {
+
+		t.Fatal("reading a truncated setfl file should fail")
+	}
+}
+
+func TestReadFuncfl(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	buf.WriteString("test potential\n29 63.55 3.615 FCC\n100 0.05 100 0.05 4.5\n")
+	tabulate(&buf, 100, 0.05, func(rho float64) float64 { return -rho })
+	tabulate(&buf, 100, 0.05, func(r float64) float64 { return 1 })
+	tabulate(&buf, 100, 0.05, func(r float64) float64 { return math.Exp(-r) })
+
+	eam, err := ReadFuncfl(&buf)
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	if eam.Elements[0] != "29" || eam.Masses[0] != 63.55 || eam.Cutoff != 4.5 {
+
+		t.Fatalf(
+			"unexpected element %s with mass %f and cutoff %f",
+			eam.Elements[0], eam.Masses[0], eam.Cutoff,
+		)
+	}
+
+	if phi, _ := eam.phi(0, 0, 2); math.Abs(phi-hartreeBohr/2) > 1e-9 {
+
+		t.Fatalf("pair potential at 2 should be %f not %f", hartreeBohr/2, phi)
+	}
+}
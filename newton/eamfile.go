@@ -0,0 +1,215 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Hartree times Bohr radius in eV A, used by funcfl files to give the pair
+// potential in terms of effective charges
+const hartreeBohr = 27.2 * 0.529
+
+// Reads an EAM potential in the multi-element setfl format of DYNAMO and
+// LAMMPS
+func ReadSetfl(r io.Reader) (*EAM, error) {
+
+	in := newWordReader(r, 3)
+
+	n := in.integer()
+	if in.err == nil && n < 1 {
+
+		return nil, fmt.Errorf("setfl: invalid element count %d", n)
+	}
+
+	eam := &EAM{Elements: make([]string, n), Masses: make([]float64, n)}
+
+	for i := range eam.Elements {
+
+		eam.Elements[i] = in.word()
+	}
+
+	nRho, dRho, nR, dR := in.integer(), in.number(), in.integer(), in.number()
+	eam.Cutoff = in.number()
+
+	if in.err == nil && (nRho < 1 || nR < 1) {
+
+		return nil, fmt.Errorf("setfl: invalid table sizes %d and %d", nRho, nR)
+	}
+
+	eam.embedding = make([]table, n)
+	eam.density = make([]table, n)
+
+	for i := range eam.Elements {
+
+		// Atomic number, mass, lattice constant and lattice type
+		in.integer()
+		eam.Masses[i] = in.number()
+		in.number()
+		in.word()
+
+		eam.embedding[i] = table{step: dRho, values: in.numbers(nRho)}
+		eam.density[i] = table{step: dR, values: in.numbers(nR)}
+	}
+
+	eam.pair = make([][]table, n)
+	for i := range eam.pair {
+
+		eam.pair[i] = make([]table, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+
+			eam.pair[i][j] = table{step: dR, values: in.numbers(nR)}
+			eam.pair[j][i] = eam.pair[i][j]
+		}
+	}
+
+	if in.err != nil {
+
+		return nil, fmt.Errorf("setfl: %s", in.err)
+	}
+
+	return eam, nil
+}
+
+// Reads a single element EAM potential in the funcfl format of DYNAMO and
+// LAMMPS
+//
+// The format doesn't name the element, so it is named by it's atomic number.
+func ReadFuncfl(r io.Reader) (*EAM, error) {
+
+	in := newWordReader(r, 1)
+
+	z := in.integer()
+	mass := in.number()
+	in.number()
+	in.word()
+
+	nRho, dRho, nR, dR := in.integer(), in.number(), in.integer(), in.number()
+	cutoff := in.number()
+
+	if in.err == nil && (nRho < 1 || nR < 1) {
+
+		return nil, fmt.Errorf("funcfl: invalid table sizes %d and %d", nRho, nR)
+	}
+
+	embedding := in.numbers(nRho)
+	charge := in.numbers(nR)
+	density := in.numbers(nR)
+
+	if in.err != nil {
+
+		return nil, fmt.Errorf("funcfl: %s", in.err)
+	}
+
+	rphi := make([]float64, nR)
+	for i, z := range charge {
+
+		rphi[i] = hartreeBohr * z * z
+	}
+
+	eam := &EAM{
+		Elements:  []string{strconv.Itoa(z)},
+		Masses:    []float64{mass},
+		Cutoff:    cutoff,
+		embedding: []table{{step: dRho, values: embedding}},
+		density:   []table{{step: dR, values: density}},
+		pair:      [][]table{{{step: dR, values: rphi}}},
+	}
+
+	return eam, nil
+}
+
+// Reads whitespace separated words after skipping some comment lines
+//
+// The first error encountered is remembered and all the reads following it
+// return zero values.
+type wordReader struct {
+	words *bufio.Scanner
+	err   error
+}
+
+func newWordReader(r io.Reader, comments int) *wordReader {
+
+	in := new(wordReader)
+
+	buffered := bufio.NewReader(r)
+
+	for i := 0; i < comments && in.err == nil; i++ {
+
+		_, in.err = buffered.ReadString('\n')
+	}
+
+	in.words = bufio.NewScanner(buffered)
+	in.words.Split(bufio.ScanWords)
+
+	return in
+}
+
+func (in *wordReader) word() string {
+
+	if in.err != nil {
+
+		return ""
+	}
+
+	if !in.words.Scan() {
+
+		in.err = in.words.Err()
+		if in.err == nil {
+
+			in.err = io.ErrUnexpectedEOF
+		}
+
+		return ""
+	}
+
+	return in.words.Text()
+}
+
+func (in *wordReader) integer() int {
+
+	w := in.word()
+	if in.err != nil {
+
+		return 0
+	}
+
+	n, err := strconv.Atoi(w)
+	in.err = err
+
+	return n
+}
+
+func (in *wordReader) number() float64 {
+
+	w := in.word()
+	if in.err != nil {
+
+		return 0
+	}
+
+	x, err := strconv.ParseFloat(w, 64)
+	in.err = err
+
+	return x
+}
+
+func (in *wordReader) numbers(n int) []float64 {
+
+	xs := make([]float64, n)
+
+	for i := range xs {
+
+		xs[i] = in.number()
+	}
+
+	return xs
+}
@@ -5,14 +5,146 @@
 package newton
 
 import (
+	"fmt"
+	"reflect"
+
 	"github.com/szabba/md/vect"
 )
 
 // A force that acts upon a body
 type Force interface {
+	// The force on the i-th body divided by it's mass, in units of force per
+	// unit of mass. A System divides it by the MVV2E of it's units to get the
+	// acceleration.
 	Accel(bs []*Body, i int, dt float64) (a vect.Vector)
 }
 
+// A force derived from a potential energy
+type Potential interface {
+	Force
+	// The potential energy stored in the whole system of bodies. It must not
+	// depend on the force having been prepared.
+	Energy(bs []*Body) float64
+}
+
+// A force that needs to look at all the bodies before accelerations are
+// computed, eg. to find the electron density around each one
+type Preparer interface {
+	Force
+	// Called once per step, before Accel is called for any of the bodies
+	Prepare(bs []*Body)
+}
+
+// Prepare the force for computing accelerations, if it needs that
+func prepare(f Force, bs []*Body) {
+
+	if p, ok := f.(Preparer); ok {
+
+		p.Prepare(bs)
+	}
+}
+
+// A force that keeps data for each body by it's index, like the springs
+// between them, and has to follow bodies being added to and removed from a
+// System
+type Tracker interface {
+	Force
+	// Called after a body was inserted at the i-th index. The ones that were
+	// at i and after it moved up by one.
+	BodyAdded(i int)
+	// Called after the i-th body was removed. The ones after it moved down by
+	// one.
+	BodyRemoved(i int)
+}
+
+// Tell the force a body was added, if it keeps track of them
+func bodyAdded(f Force, i int) {
+
+	if t, ok := f.(Tracker); ok {
+
+		t.BodyAdded(i)
+
+	} else {
+
+		mustNotTrack(f)
+	}
+}
+
+// Tell the force a body was removed, if it keeps track of them
+func bodyRemoved(f Force, i int) {
+
+	if t, ok := f.(Tracker); ok {
+
+		t.BodyRemoved(i)
+
+	} else {
+
+		mustNotTrack(f)
+	}
+}
+
+var trackerType = reflect.TypeOf((*Tracker)(nil)).Elem()
+
+// A force whose pointer is a Tracker keeps data for each body, but a copy of
+// it can't follow the bodies and would index them wrongly, so this panics
+// instead.
+func mustNotTrack(f Force) {
+
+	if t := reflect.TypeOf(f); t != nil && reflect.PtrTo(t).Implements(trackerType) {
+
+		panic(fmt.Sprintf("newton: a %T force can't follow bodies being added or removed unless it's set by pointer", f))
+	}
+}
+
+// Inserts a zero value at the i-th index of a per-body slice. A nil slice
+// stays nil.
+func insertAt(s []int, i int) []int {
+
+	if s == nil {
+
+		return nil
+	}
+
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = 0
+
+	return s
+}
+
+// Removes the i-th index of a per-body slice
+func removeAt(s []int, i int) []int {
+
+	if s == nil {
+
+		return nil
+	}
+
+	return append(s[:i], s[i+1:]...)
+}
+
+// Moves a body index to follow the insertion of a body at the i-th index
+func indexAfterAdding(index, i int) int {
+
+	if index >= i {
+
+		return index + 1
+	}
+
+	return index
+}
+
+// Moves a body index to follow the removal of the i-th body
+func indexAfterRemoving(index, i int) int {
+
+	if index > i {
+
+		return index - 1
+	}
+
+	return index
+}
+
 // A combination of simple forces
 type SumForce []Force
 
@@ -28,6 +160,87 @@ func (sf SumForce) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
 	return
 }
 
+// Adds the accelerations due to all the forces in the combination, in one
+// pass for those that are batch forces
+func (sf SumForce) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	for _, f := range sf {
+
+		addAccels(f, bs, as, dt, tally)
+	}
+}
+
+// Adds a share of the accelerations due to each of the forces in the
+// combination
+func (sf SumForce) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	for _, f := range sf {
+
+		addAccelsPart(f, bs, as, part, parts, dt, tally)
+	}
+}
+
+// Prepare all the forces in the combination
+func (sf SumForce) Prepare(bs []*Body) {
+
+	for _, f := range sf {
+
+		prepare(f, bs)
+	}
+}
+
+// Tell all the forces in the combination a body was added
+func (sf SumForce) BodyAdded(i int) {
+
+	for _, f := range sf {
+
+		bodyAdded(f, i)
+	}
+}
+
+// Tell all the forces in the combination a body was removed
+func (sf SumForce) BodyRemoved(i int) {
+
+	for _, f := range sf {
+
+		bodyRemoved(f, i)
+	}
+}
+
+// The total energy of the potentials in the combination. Forces that are not
+// Potentials do not contribute.
+func (sf SumForce) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for _, f := range sf {
+
+		if p, ok := f.(Potential); ok {
+
+			u += p.Energy(bs)
+		}
+	}
+
+	return u
+}
+
+// The total virial of the forces in the combination. Forces that don't
+// implement Virial do not contribute.
+func (sf SumForce) Virial(bs []*Body) Tensor {
+
+	var w Tensor
+
+	for _, f := range sf {
+
+		if v, ok := f.(Virial); ok {
+
+			w = w.Plus(v.Virial(bs))
+		}
+	}
+
+	return w
+}
+
 // Combines multiple forces into one.
 //
 // If any of the combined forces is a SumForce, the result is flattened.
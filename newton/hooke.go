@@ -6,10 +6,14 @@ package newton
 
 import (
 	"github.com/szabba/md/vect"
+	"math"
 )
 
 type Spring struct {
 	K, L0 float64
+	// Coefficient of a dashpot parallel to the spring, damping the relative
+	// motion of the ends along it
+	Damping float64
 }
 
 type Hooke struct {
@@ -26,10 +30,132 @@ func (h Hooke) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
 
 		spring := h.Springs[i][j]
 
-		dir, l := b2.Xs[0].Minus(b.Xs[0]).UnitAndNorm()
+		dir, l := Separation(b, b2).UnitAndNorm()
 
 		f = f.Plus(dir.Scale(spring.K * (l - spring.L0)))
+
+		if spring.Damping != 0 {
+
+			f = f.Plus(dashpot(bs, i, j, dir, spring.Damping))
+		}
 	}
 
 	return f.Scale(1 / b.Mass())
 }
+
+// The energy stored in all the springs
+func (h Hooke) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for i := range bs {
+		for j := i + 1; j < len(bs); j++ {
+
+			spring := h.Springs[i][j]
+
+			l := separation(bs, i, j).Norm()
+
+			u += spring.K * math.Pow(l-spring.L0, 2) / 2
+		}
+	}
+
+	return u
+}
+
+// The virial of all the springs, without the dashpots
+func (h Hooke) Virial(bs []*Body) Tensor {
+
+	var w Tensor
+
+	for i := range bs {
+		for j := i + 1; j < len(bs); j++ {
+
+			spring := h.Springs[i][j]
+
+			d := separation(bs, i, j)
+
+			w = w.Plus(pairVirial(d, spring.K*(d.Norm()-spring.L0)))
+		}
+	}
+
+	return w
+}
+
+// Adds the accelerations due to all the springs, computing each of them once
+// when Springs is symmetric
+func (h Hooke) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	h.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Adds the accelerations due to the springs from every parts-th body to the
+// ones after it
+func (h Hooke) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	for i := part; i < len(bs); i += parts {
+		for j := i + 1; j < len(bs); j++ {
+
+			ij, ji := h.Springs[i][j], h.Springs[j][i]
+
+			d := separation(bs, i, j)
+			dir, l := d.UnitAndNorm()
+
+			f := dir.Scale(ij.K * (l - ij.L0))
+			if ij.Damping != 0 {
+
+				f = f.Plus(dashpot(bs, i, j, dir, ij.Damping))
+			}
+
+			back := f.Negate()
+			if ji != ij {
+
+				back = dir.Scale(-ji.K * (l - ji.L0))
+				if ji.Damping != 0 {
+
+					back = back.Plus(dashpot(bs, j, i, dir.Negate(), ji.Damping))
+				}
+			}
+
+			as[i] = as[i].Plus(f.Scale(1 / bs[i].Mass()))
+			as[j] = as[j].Plus(back.Scale(1 / bs[j].Mass()))
+
+			if tally != nil {
+
+				tally.Energy += ij.K * math.Pow(l-ij.L0, 2) / 2
+				tally.Virial = tally.Virial.Plus(pairVirial(d, ij.K*(l-ij.L0)))
+			}
+		}
+	}
+}
+
+// Follows a body being added. The new body is not attached to any springs.
+//
+// Only a *Hooke can follow the bodies of a System.
+func (h *Hooke) BodyAdded(i int) {
+
+	for k, row := range h.Springs {
+
+		row = append(row, Spring{})
+		copy(row[i+1:], row[i:])
+		row[i] = Spring{}
+
+		h.Springs[k] = row
+	}
+
+	h.Springs = append(h.Springs, nil)
+	copy(h.Springs[i+1:], h.Springs[i:])
+	h.Springs[i] = make([]Spring, len(h.Springs))
+}
+
+// Follows a body being removed, with all the springs attached to it
+//
+// Only a *Hooke can follow the bodies of a System.
+func (h *Hooke) BodyRemoved(i int) {
+
+	h.Springs = append(h.Springs[:i], h.Springs[i+1:]...)
+
+	for k, row := range h.Springs {
+
+		h.Springs[k] = append(row[:i], row[i+1:]...)
+	}
+}
@@ -0,0 +1,91 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// A rectangular periodic box with Lees-Edwards sliding boundaries, imposing a
+// planar Couette flow
+//
+//	u_x = ShearRate y
+//
+// The images above and below the box slide along x with the velocity of the
+// flow at their height, so their offset grows with the time read from the
+// clock. The box is meant to be used with the SLLOD integrator, which keeps
+// peculiar velocities, so that the bodies crossing the sliding boundaries
+// need no velocity correction. The shear viscosity is then
+//
+//	eta = -<P_xy> / ShearRate
+//
+// where P is the System's PressureTensor.
+//
+// Unwrapped positions are computed using the current offset, so they are
+// only approximate for bodies that crossed the sliding boundaries.
+type LeesEdwards struct {
+	Orthorhombic
+	ShearRate float64
+	clock     Clock
+}
+
+// Constructs a sheared box with the given edge lengths, that reads the time
+// from the clock. All the lengths must be nonzero.
+func NewLeesEdwards(lengths vect.Vector, shearRate float64, clock Clock) *LeesEdwards {
+
+	return &LeesEdwards{
+		Orthorhombic: Orthorhombic{lengths: components(lengths)},
+		ShearRate:    shearRate,
+		clock:        clock,
+	}
+}
+
+// The distance along x the images above the box moved by since time zero
+func (le *LeesEdwards) Strain() float64 {
+
+	return le.ShearRate * le.lengths[1] * le.clock.Time()
+}
+
+// The current offset along x of the images above the box, in [0, Lx)
+func (le *LeesEdwards) Offset() float64 {
+
+	lx := le.lengths[0]
+
+	return le.Strain() - lx*math.Floor(le.Strain()/lx)
+}
+
+func (le *LeesEdwards) Separation(x, y vect.Vector) vect.Vector {
+
+	d := components(y.Minus(x))
+
+	n := math.Floor(d[1]/le.lengths[1] + 0.5)
+	d[1] -= n * le.lengths[1]
+	d[0] -= n * le.Offset()
+
+	return le.Orthorhombic.Separation(vect.Zero, fromComponents(d))
+}
+
+func (le *LeesEdwards) Wrap(x vect.Vector) (wrapped vect.Vector, image [3]int) {
+
+	xs := components(x)
+
+	n := math.Floor(xs[1] / le.lengths[1])
+	xs[1] -= n * le.lengths[1]
+	xs[0] -= n * le.Offset()
+
+	wrapped, image = le.Orthorhombic.Wrap(fromComponents(xs))
+	image[1] = int(n)
+
+	return wrapped, image
+}
+
+func (le *LeesEdwards) Translation(image [3]int) vect.Vector {
+
+	t := le.Orthorhombic.Translation(image)
+
+	return t.Plus(vect.UnitX.Scale(float64(image[1]) * le.Offset()))
+}
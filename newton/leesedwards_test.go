@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestSlidingImages(t *testing.T) {
+
+	le := NewLeesEdwards(vect.NewVector(10, 10, 10), 0.5, fixedClock(1))
+
+	if le.Offset() != 5 {
+
+		t.Fatalf("offset should be 5 not %f", le.Offset())
+	}
+
+	// The image of the upper body below the box is shifted back by the offset
+	d := le.Separation(vect.NewVector(1, 0.5, 0), vect.NewVector(7, 9.5, 0))
+	if expected := vect.NewVector(1, -1, 0); !near(d, expected) {
+
+		t.Fatalf("separation should be %v not %v", expected, d)
+	}
+
+	wrapped, image := le.Wrap(vect.NewVector(8, 10.5, 0))
+	if expected := vect.NewVector(3, 0.5, 0); !near(wrapped, expected) || image != [3]int{0, 1, 0} {
+
+		t.Fatalf("wrapped position should be %v in image (0, 1, 0), not %v in %v", expected, wrapped, image)
+	}
+}
+
+func TestSLLODFollowsTheFlow(t *testing.T) {
+
+	rate := 0.2
+
+	sys := NewSystem(SLLOD{ShearRate: rate}, 1)
+	sys.SetForce(LinearDrag{})
+	sys.SetBox(NewLeesEdwards(vect.NewVector(10, 10, 10), rate, sys))
+
+	b := sys.Body(0)
+	b.SetMass(1)
+	b.SetNow(vect.NewVector(1, 8, 5), vect.Zero)
+
+	for i := 0; i < 100; i++ {
+
+		sys.Step(0.05)
+	}
+
+	if expected := vect.NewVector(9, 8, 5); !near(b.XNow(), expected) {
+
+		t.Fatalf("a body at rest relative to the flow should be at %v not %v", expected, b.XNow())
+	}
+
+	if !near(b.VNow(), vect.Zero) {
+
+		t.Fatalf("the peculiar velocity should stay zero, not %v", b.VNow())
+	}
+}
@@ -0,0 +1,201 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/szabba/md/vect"
+)
+
+// Accelerations of all the bodies, computed at once by a many-body potential
+// when it is prepared, and the positions they were computed at
+type manyBody struct {
+	as, at []vect.Vector
+}
+
+// Remember the accelerations due to the forces f
+func (mb *manyBody) store(bs []*Body, f []vect.Vector) {
+
+	mb.as = make([]vect.Vector, len(bs))
+
+	for i, b := range bs {
+
+		mb.as[i] = f[i].Scale(1 / b.Mass())
+	}
+
+	mb.at = positionsOf(bs)
+}
+
+// The acceleration of the i-th body computed when the potential was prepared
+func (mb *manyBody) accel(name string, bs []*Body, i int) vect.Vector {
+
+	mustBePrepared(name, mb.at, bs, i)
+
+	return mb.as[i]
+}
+
+// Forget the accelerations, once they belong to other bodies
+func (mb *manyBody) forget() {
+
+	mb.as, mb.at = nil, nil
+}
+
+// The latest positions of the bodies
+func positionsOf(bs []*Body) []vect.Vector {
+
+	at := make([]vect.Vector, len(bs))
+
+	for i, b := range bs {
+
+		at[i] = b.XLatest()
+	}
+
+	return at
+}
+
+// Many-body potentials compute all the forces in Prepare, which could not
+// safely be redone from Accel, called concurrently for different bodies. When
+// the i-th body has moved or the bodies changed since the positions at were
+// seen in Prepare, the results would be stale, so this panics instead.
+func mustBePrepared(name string, at []vect.Vector, bs []*Body, i int) {
+
+	if len(at) != len(bs) || at[i] != bs[i].XLatest() {
+
+		panic(name + ": Accel called without Prepare after the bodies changed")
+	}
+}
+
+// Adds the forces on the i-th and j-th bodies due to an energy that depends
+// on their separation r, with a derivative dEdr, and their virial to w. The
+// unit vector u points from the i-th body to the j-th one.
+func addPair(f []vect.Vector, w *Tensor, i, j int, u vect.Vector, r, dEdr float64) {
+
+	f[i] = f[i].Plus(u.Scale(dEdr))
+	f[j] = f[j].Minus(u.Scale(dEdr))
+
+	*w = w.Plus(pairVirial(u.Scale(r), dEdr))
+}
+
+// Adds the forces due to an energy that depends on the separations r1 and r2
+// of the j-th and k-th bodies from the i-th one and the cosine of the angle
+// between them, and their virial to w. The unit vectors u1 and u2 point from
+// the i-th body towards the other two.
+func addTriplet(
+	f []vect.Vector, w *Tensor, i, j, k int,
+	u1, u2 vect.Vector, r1, r2, cos float64,
+	dEdr1, dEdr2, dEdcos float64,
+) {
+
+	fj := u1.Scale(dEdr1).Plus(u2.Minus(u1.Scale(cos)).Scale(dEdcos / r1)).Negate()
+	fk := u2.Scale(dEdr2).Plus(u1.Minus(u2.Scale(cos)).Scale(dEdcos / r2)).Negate()
+
+	f[j] = f[j].Plus(fj)
+	f[k] = f[k].Plus(fk)
+	f[i] = f[i].Minus(fj.Plus(fk))
+
+	*w = w.Plus(Outer(u1.Scale(r1), fj)).Plus(Outer(u2.Scale(r2), fk))
+}
+
+// The element of the i-th body, as an index into a list of elements. When
+// types is nil, all the bodies are of the first element.
+func elementOf(types []int, i int) int {
+
+	if types == nil {
+
+		return 0
+	}
+
+	return types[i]
+}
+
+// Reads a parameter file in the format used by LAMMPS for three-body
+// potentials -- entries of three element names followed by n numbers. Text
+// following a # is a comment.
+//
+// The entries for all the triplets of the given elements are passed to set
+// together with the indices of the elements. Entries for other elements are
+// ignored.
+func readTriplets(
+	r io.Reader, n int, elements []string,
+	set func(i, j, k int, params []float64),
+) error {
+
+	var words []string
+
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+
+		line := lines.Text()
+		if at := strings.Index(line, "#"); at >= 0 {
+
+			line = line[:at]
+		}
+
+		words = append(words, strings.Fields(line)...)
+	}
+	if err := lines.Err(); err != nil {
+
+		return err
+	}
+
+	if len(words)%(3+n) != 0 {
+
+		return fmt.Errorf("incomplete entry in parameter file")
+	}
+
+	index := make(map[string]int)
+	for i, e := range elements {
+
+		index[e] = i
+	}
+
+	found := make(map[[3]int]bool)
+
+	for len(words) > 0 {
+
+		entry := words[:3+n]
+		words = words[3+n:]
+
+		i, iOk := index[entry[0]]
+		j, jOk := index[entry[1]]
+		k, kOk := index[entry[2]]
+		if !iOk || !jOk || !kOk {
+			continue
+		}
+
+		params := make([]float64, n)
+		for p := range params {
+
+			x, err := strconv.ParseFloat(entry[3+p], 64)
+			if err != nil {
+
+				return err
+			}
+			params[p] = x
+		}
+
+		set(i, j, k, params)
+		found[[3]int{i, j, k}] = true
+	}
+
+	if len(found) != len(elements)*len(elements)*len(elements) {
+
+		return fmt.Errorf("parameter file doesn't cover all triplets of %v", elements)
+	}
+
+	return nil
+}
+
+// The position of the parameters for the triplet of elements i, j, k among
+// the parameters for all the n^3 triplets
+func tripletAt(n, i, j, k int) int {
+
+	return (i*n+j)*n + k
+}
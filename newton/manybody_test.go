@@ -0,0 +1,143 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+const siliconSW = `
+# Stillinger and Weber, Phys Rev B, 31, 5262 (1985)
+# elements epsilon sigma a lambda gamma costheta0 A B p q tol
+Si Si Si 2.1683 2.0951 1.80 21.0 1.20 -0.333333333333
+         7.049556277 0.6022245584 4.0 0.0 0.0
+`
+
+const siliconTersoff = `
+# Tersoff, Phys Rev B, 37, 6991 (1988)
+Si Si Si 3.0 1.0 1.3258 4.8381 2.0417 0.0 22.956
+         0.33675 1.3258 95.373 3.0 0.2 3.2394 3160.1
+`
+
+// A distorted fragment of the diamond lattice of silicon
+func siliconCluster() []*Body {
+
+	return bodiesAt(
+		vect.NewVector(0, 0, 0),
+		vect.NewVector(1.40, 1.33, 1.35),
+		vect.NewVector(-1.31, -1.38, 1.36),
+		vect.NewVector(1.37, -1.35, -1.30),
+		vect.NewVector(-1.34, 1.36, -1.39),
+		vect.NewVector(2.65, 2.80, 0.05),
+	)
+}
+
+func TestStillingerWeberForcesMatchEnergy(t *testing.T) {
+
+	sw, err := ReadStillingerWeber(strings.NewReader(siliconSW), "Si")
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	if p := sw.Param(0, 0, 0); p.Lambda != 21 || p.P != 4 {
+
+		t.Fatalf("unexpected parameters %+v", p)
+	}
+
+	forceMatchesEnergy(sw, siliconCluster(), t)
+}
+
+func TestTersoffForcesMatchEnergy(t *testing.T) {
+
+	tersoff, err := ReadTersoff(strings.NewReader(siliconTersoff), "Si")
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	forceMatchesEnergy(tersoff, siliconCluster(), t)
+}
+
+func TestManyBodyVirials(t *testing.T) {
+
+	sw, err := ReadStillingerWeber(strings.NewReader(siliconSW), "Si")
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	tersoff, err := ReadTersoff(strings.NewReader(siliconTersoff), "Si")
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	eam, err := ReadSetfl(strings.NewReader(testSetfl()))
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	for _, f := range []Virial{sw, tersoff, eam} {
+
+		virialMatchesForces(f, siliconCluster(), t)
+	}
+}
+
+func TestMissingTripletIsAnError(t *testing.T) {
+
+	_, err := ReadTersoff(strings.NewReader(siliconTersoff), "Si", "C")
+	if err == nil {
+
+		t.Fatal("reading parameters for Si and C from a file only for Si should fail")
+	}
+}
+
+// Whether Accel panicked for the first body
+func accelPanics(f Force, bs []*Body) (panicked bool) {
+
+	defer func() { panicked = recover() != nil }()
+
+	f.Accel(bs, 0, 0)
+
+	return false
+}
+
+func TestManyBodyForcesRequirePrepare(t *testing.T) {
+
+	sw, _ := ReadStillingerWeber(strings.NewReader(siliconSW), "Si")
+	tersoff, _ := ReadTersoff(strings.NewReader(siliconTersoff), "Si")
+	eam, err := ReadSetfl(strings.NewReader(testSetfl()))
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	for name, f := range map[string]Force{"sw": sw, "tersoff": tersoff, "eam": eam} {
+
+		bs := siliconCluster()
+
+		if !accelPanics(f, bs) {
+
+			t.Errorf("%s: Accel should not work before Prepare", name)
+		}
+
+		prepare(f, bs)
+		if accelPanics(f, bs) {
+
+			t.Errorf("%s: Accel should work after Prepare", name)
+		}
+
+		bs[0].Xs[0] = bs[0].Xs[0].Plus(vect.UnitX.Scale(0.1))
+		if !accelPanics(f, bs) {
+
+			t.Errorf("%s: Accel should not use results from before the bodies moved", name)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// The default skin of the neighbour lists a System creates
+const DefaultSkin = 0.3
+
+// A Verlet neighbour list -- for each body, the other bodies closer to it
+// than the cutoff plus a skin distance
+//
+// As long as no body has moved more than half the skin since the list was
+// built, all the pairs closer than the cutoff are in it. Only then does it
+// need to be rebuilt, which is done using a cell list.
+type NeighbourList struct {
+	cutoff, skin float64
+
+	cells *CellList
+	nbs   [][]int
+	// The positions and IDs of the bodies and the strain of a sheared box
+	// when the list was built
+	at       []vect.Vector
+	ids      []int
+	strainAt float64
+
+	rebuilds int
+}
+
+// Constructs an empty neighbour list
+func NewNeighbourList(cutoff, skin float64) *NeighbourList {
+
+	return &NeighbourList{cutoff: cutoff, skin: skin}
+}
+
+// The distance within which the list is guaranteed to contain all the pairs
+func (nl *NeighbourList) Cutoff() float64 {
+
+	return nl.cutoff
+}
+
+// The extra distance beyond the cutoff, that bodies can move by before the
+// list needs rebuilding
+func (nl *NeighbourList) Skin() float64 {
+
+	return nl.skin
+}
+
+// The number of times the list has been built
+func (nl *NeighbourList) Rebuilds() int {
+
+	return nl.rebuilds
+}
+
+// Rebuild the list if any of the bodies moved more than half the skin since
+// the last build, or the bodies or their box changed. Bodies are told apart
+// by their IDs, so removing a body and adding another also needs a rebuild.
+func (nl *NeighbourList) Update(bs []*Body) (rebuilt bool) {
+
+	if nl.cells == nil && nl.at != nil {
+
+		// Restored from a checkpoint, for bodies in the same box
+		nl.cells = NewCellListIn(boxOf(bs), nl.cutoff+nl.skin)
+	}
+
+	if nl.cells != nil && nl.cells.Box() == boxOf(bs) && !nl.changed(bs) && !nl.moved(bs) {
+
+		return false
+	}
+
+	if nl.cells == nil || nl.cells.Box() != boxOf(bs) {
+
+		nl.cells = NewCellListIn(boxOf(bs), nl.cutoff+nl.skin)
+	}
+
+	nl.nbs = nl.cells.Neighbours(bs)
+
+	nl.strainAt = strainOf(boxOf(bs))
+
+	nl.at = make([]vect.Vector, len(bs))
+	nl.ids = make([]int, len(bs))
+	for i, b := range bs {
+
+		nl.at[i], nl.ids[i] = b.Xs[0], b.ID()
+	}
+
+	nl.rebuilds++
+
+	return true
+}
+
+// The candidate neighbours of the i-th body. Some of them might be further
+// away than the cutoff.
+func (nl *NeighbourList) Of(i int) []int {
+
+	return nl.nbs[i]
+}
+
+// The candidate neighbours of all the bodies
+func (nl *NeighbourList) Lists() [][]int {
+
+	return nl.nbs
+}
+
+// Are the bodies other than the ones the list was built for?
+func (nl *NeighbourList) changed(bs []*Body) bool {
+
+	if len(nl.ids) != len(bs) {
+
+		return true
+	}
+
+	for i, b := range bs {
+
+		if b.ID() != nl.ids[i] {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Has any of the bodies moved more than half the skin since the last build?
+//
+// In a sheared box, the images sliding past each other use up some of the
+// skin.
+func (nl *NeighbourList) moved(bs []*Body) bool {
+
+	slack := (nl.skin - math.Abs(strainOf(boxOf(bs))-nl.strainAt)) / 2
+
+	for i, b := range bs {
+
+		if nl.cells.Separation(nl.at[i], b.Xs[0]).Norm() > slack {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// The name of the checkpoint section holding the list
+func (nl *NeighbourList) Section() string {
+
+	return fmt.Sprintf("neighbours %g %g", nl.cutoff, nl.skin)
+}
+
+// Saves the list with the positions and IDs of the bodies it was built for,
+// so that a restarted run rebuilds it at the same steps
+func (nl *NeighbourList) MarshalBinary() ([]byte, error) {
+
+	var sec sectionWriter
+
+	sec.u64(uint64(nl.rebuilds))
+	sec.f64(nl.strainAt)
+	sec.u64(uint64(len(nl.at)))
+
+	for i := range nl.at {
+
+		sec.vec(nl.at[i])
+		sec.i64(int64(nl.ids[i]))
+		sec.u64(uint64(len(nl.nbs[i])))
+
+		for _, j := range nl.nbs[i] {
+
+			sec.u64(uint64(j))
+		}
+	}
+
+	return sec.buf.Bytes(), nil
+}
+
+// Restores a list saved with MarshalBinary. The box of the bodies is taken to
+// be the one it was built in.
+func (nl *NeighbourList) UnmarshalBinary(data []byte) error {
+
+	sec := &sectionReader{data: data}
+
+	rebuilds := int(sec.u64())
+	strainAt := sec.f64()
+
+	// A body takes up at least it's position, ID and neighbour count
+	n := sec.u64()
+	if n > uint64(len(sec.data)/(3*8+8+8)) {
+
+		return fmt.Errorf("%d bodies don't fit in %d bytes", n, len(sec.data))
+	}
+
+	at := make([]vect.Vector, n)
+	ids := make([]int, n)
+	nbs := make([][]int, n)
+
+	for i := range at {
+
+		at[i], ids[i] = sec.vec(), int(sec.i64())
+
+		count := sec.u64()
+		if count >= n {
+
+			return fmt.Errorf("body %d has %d neighbours among %d bodies", i, count, n)
+		}
+
+		for k := uint64(0); k < count && sec.err == nil; k++ {
+
+			j := sec.u64()
+			if j >= n {
+
+				return fmt.Errorf("body %d has a neighbour %d among %d bodies", i, j, n)
+			}
+
+			nbs[i] = append(nbs[i], int(j))
+		}
+	}
+
+	if sec.err != nil {
+
+		return sec.err
+	}
+
+	nl.rebuilds, nl.strainAt = rebuilds, strainAt
+	nl.at, nl.ids, nl.nbs = at, ids, nbs
+	nl.cells = nil
+
+	return nil
+}
+
+// How far the images of a sheared box slid, zero for other boxes
+func strainOf(box Box) float64 {
+
+	if le, ok := box.(*LeesEdwards); ok {
+
+		return le.Strain()
+	}
+
+	return 0
+}
+
+// For each body, the candidates for bodies closer to it than the cutoff --
+// taken from a neighbour list when one is given and found with a cell list
+// otherwise. It panics when the list's cutoff is too short to have all of
+// them.
+func neighboursFor(nl *NeighbourList, bs []*Body, cutoff float64) [][]int {
+
+	if nl == nil {
+
+		return neighbours(bs, cutoff)
+	}
+
+	if nl.Cutoff() < cutoff {
+
+		panic(fmt.Sprintf(
+			"newton: a neighbour list with a cutoff of %g is used for a cutoff of %g",
+			nl.Cutoff(), cutoff,
+		))
+	}
+
+	nl.Update(bs)
+
+	return nl.Lists()
+}
@@ -0,0 +1,355 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// Bins bodies into cells at least as wide as a cutoff, so that only bodies in
+// adjacent cells need to be checked for being closer than it. This makes
+// finding all the close pairs O(N).
+//
+// Along each direction the box is either open -- the cells then span the
+// bodies' bounding box -- or periodic. The box should be at least twice the
+// cutoff across in the periodic directions.
+type CellList struct {
+	cutoff float64
+	box    Box
+	period [3]float64
+	// Are the images above and below the box sliding, like with Lees-Edwards
+	// boundaries?
+	sheared bool
+
+	origin, size [3]float64
+	dims         [3]int
+
+	// The first body in each cell and the next body in the same cell as
+	// each body, -1 ending the chain
+	head, next []int
+}
+
+// Constructs a cell list for open space
+func NewCellList(cutoff float64) *CellList {
+
+	return NewCellListIn(Open, cutoff)
+}
+
+// Constructs a cell list for bodies in the given box
+func NewCellListIn(box Box, cutoff float64) *CellList {
+
+	cl := &CellList{cutoff: cutoff, box: box, period: box.periods()}
+
+	_, cl.sheared = box.(*LeesEdwards)
+
+	return cl
+}
+
+// The box the cell list bins bodies in
+func (cl *CellList) Box() Box {
+
+	return cl.box
+}
+
+// The distance within which the cell list finds pairs
+func (cl *CellList) Cutoff() float64 {
+
+	return cl.cutoff
+}
+
+// Bin the bodies by their latest positions
+func (cl *CellList) Build(bs []*Body) {
+
+	cl.layOut(bs)
+
+	cells := cl.dims[0] * cl.dims[1] * cl.dims[2]
+
+	cl.head = resize(cl.head, cells)
+	cl.next = resize(cl.next, len(bs))
+
+	for c := range cl.head {
+
+		cl.head[c] = -1
+	}
+
+	for i, b := range bs {
+
+		c := cl.cellIndex(cl.cellOf(b.Xs[0]))
+
+		cl.next[i], cl.head[c] = cl.head[c], i
+	}
+}
+
+// Calls do for each pair of bodies closer than the cutoff, with i < j and the
+// separation vector pointing from the i-th to the j-th body. The cell list
+// must be built first.
+func (cl *CellList) EachPair(bs []*Body, do func(i, j int, d vect.Vector)) {
+
+	var near []int
+
+	for i, b := range bs {
+
+		near = cl.adjacent(cl.cellOf(b.Xs[0]), near[:0])
+
+		for _, c := range near {
+			for j := cl.head[c]; j >= 0; j = cl.next[j] {
+
+				if j <= i {
+					continue
+				}
+
+				d := cl.Separation(b.Xs[0], bs[j].Xs[0])
+
+				if d.Norm() < cl.cutoff {
+
+					do(i, j, d)
+				}
+			}
+		}
+	}
+}
+
+// Rebuilds the cell list and finds the neighbours of each body -- the other
+// bodies closer to it than the cutoff
+func (cl *CellList) Neighbours(bs []*Body) [][]int {
+
+	cl.Build(bs)
+
+	nbs := make([][]int, len(bs))
+
+	cl.EachPair(bs, func(i, j int, _ vect.Vector) {
+
+		nbs[i] = append(nbs[i], j)
+		nbs[j] = append(nbs[j], i)
+	})
+
+	return nbs
+}
+
+// The vector from x to the nearest image of y in the box
+func (cl *CellList) Separation(x, y vect.Vector) vect.Vector {
+
+	return cl.box.Separation(x, y)
+}
+
+// Choose the cell grid for the bodies
+func (cl *CellList) layOut(bs []*Body) {
+
+	lo, hi := cl.boundingBox(bs)
+
+	for ax := range cl.dims {
+
+		extent := hi[ax] - lo[ax]
+		cl.origin[ax] = lo[ax]
+
+		if l := cl.period[ax]; l > 0 {
+
+			extent = l
+			cl.origin[ax] = 0
+		}
+
+		cl.dims[ax] = int(extent / cl.cutoff)
+		if cl.dims[ax] < 1 {
+
+			cl.dims[ax] = 1
+		}
+	}
+
+	// Sparse bodies in open space could need more cells than there are
+	// bodies, so the cells are made coarser
+	limit := float64(4*len(bs) + 27)
+	cells := float64(cl.dims[0] * cl.dims[1] * cl.dims[2])
+
+	if cells > limit {
+
+		shrink := math.Cbrt(cells / limit)
+
+		for ax := range cl.dims {
+
+			if cl.period[ax] == 0 {
+
+				cl.dims[ax] = int(math.Max(1, float64(cl.dims[ax])/shrink))
+			}
+		}
+	}
+
+	for ax := range cl.size {
+
+		extent := hi[ax] - lo[ax]
+		if cl.period[ax] > 0 {
+
+			extent = cl.period[ax]
+		}
+
+		cl.size[ax] = math.Max(extent/float64(cl.dims[ax]), cl.cutoff)
+	}
+}
+
+// The grid coordinates of the cell containing x
+func (cl *CellList) cellOf(x vect.Vector) (cell [3]int) {
+
+	xs := cl.box.coordinates(x)
+
+	for ax := range cell {
+
+		u := xs[ax] - cl.origin[ax]
+		if l := cl.period[ax]; l > 0 {
+
+			u -= l * math.Floor(u/l)
+		}
+
+		cell[ax] = int(u / cl.size[ax])
+		if cell[ax] >= cl.dims[ax] {
+
+			cell[ax] = cl.dims[ax] - 1
+		}
+		if cell[ax] < 0 {
+
+			cell[ax] = 0
+		}
+	}
+
+	return cell
+}
+
+func (cl *CellList) cellIndex(cell [3]int) int {
+
+	return (cell[0]*cl.dims[1]+cell[1])*cl.dims[2] + cell[2]
+}
+
+// Appends the indices of the cells adjacent to the given one (including
+// itself) to near, each only once
+func (cl *CellList) adjacent(cell [3]int, near []int) []int {
+
+	var shift [3]int
+
+	for shift[0] = -1; shift[0] <= 1; shift[0]++ {
+		for shift[1] = -1; shift[1] <= 1; shift[1]++ {
+			for shift[2] = -1; shift[2] <= 1; shift[2]++ {
+
+				other, ok := cl.shifted(cell, shift)
+				if !ok {
+					continue
+				}
+
+				// The cells of sliding images are not aligned with the box's,
+				// so all of the ones in the row need checking
+				if cl.sheared && other[1] != cell[1]+shift[1] {
+
+					for other[0] = 0; other[0] < cl.dims[0]; other[0]++ {
+
+						near = addOnce(near, cl.cellIndex(other))
+					}
+
+					continue
+				}
+
+				near = addOnce(near, cl.cellIndex(other))
+			}
+		}
+	}
+
+	return near
+}
+
+// The cell shifted from the given one. Along periodic axes the grid wraps
+// around, along open ones shifting out of the grid fails.
+func (cl *CellList) shifted(cell, shift [3]int) (other [3]int, ok bool) {
+
+	for ax := range cell {
+
+		other[ax] = cell[ax] + shift[ax]
+
+		if cl.period[ax] > 0 {
+
+			other[ax] = (other[ax] + cl.dims[ax]) % cl.dims[ax]
+
+		} else if other[ax] < 0 || other[ax] >= cl.dims[ax] {
+
+			return other, false
+		}
+	}
+
+	return other, true
+}
+
+// The corners of the smallest box containing the latest positions of all the
+// bodies, in the coordinates of the cell grid
+func (cl *CellList) boundingBox(bs []*Body) (lo, hi [3]float64) {
+
+	for i, b := range bs {
+
+		xs := cl.box.coordinates(b.Xs[0])
+
+		for ax, x := range xs {
+
+			if i == 0 || x < lo[ax] {
+
+				lo[ax] = x
+			}
+			if i == 0 || x > hi[ax] {
+
+				hi[ax] = x
+			}
+		}
+	}
+
+	return lo, hi
+}
+
+// For each body, the indices of the other bodies closer to it than the cutoff
+func neighbours(bs []*Body, cutoff float64) [][]int {
+
+	return NewCellListIn(boxOf(bs), cutoff).Neighbours(bs)
+}
+
+// The cartesian components of a vector
+func components(v vect.Vector) [3]float64 {
+
+	return [3]float64{v.Dot(vect.UnitX), v.Dot(vect.UnitY), v.Dot(vect.UnitZ)}
+}
+
+// The vector with the given cartesian components
+func fromComponents(xs [3]float64) vect.Vector {
+
+	return vect.NewVector(xs[0], xs[1], xs[2])
+}
+
+// A slice of length n, reusing the storage of xs when possible
+func resize(xs []int, n int) []int {
+
+	if cap(xs) < n {
+
+		return make([]int, n)
+	}
+
+	return xs[:n]
+}
+
+// Appends x to xs unless it's already there
+func addOnce(xs []int, x int) []int {
+
+	if contains(xs, x) {
+
+		return xs
+	}
+
+	return append(xs, x)
+}
+
+func contains(xs []int, x int) bool {
+
+	for _, y := range xs {
+
+		if y == x {
+
+			return true
+		}
+	}
+
+	return false
+}
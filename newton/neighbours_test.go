@@ -0,0 +1,182 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func randomBodies(n int, size float64, rng *rand.Rand) []*Body {
+
+	xs := make([]vect.Vector, n)
+
+	for i := range xs {
+
+		xs[i] = vect.NewVector(
+			size*rng.Float64(), size*rng.Float64(), size*rng.Float64(),
+		)
+	}
+
+	return bodiesAt(xs...)
+}
+
+// Checks the cell list finds exactly the pairs a brute force search does
+func cellsMatchBruteForce(cl *CellList, bs []*Body, t *testing.T) {
+
+	found := make(map[[2]int]bool)
+
+	cl.Build(bs)
+	cl.EachPair(bs, func(i, j int, _ vect.Vector) {
+
+		if found[[2]int{i, j}] {
+
+			t.Errorf("pair (%d, %d) found more than once", i, j)
+		}
+		found[[2]int{i, j}] = true
+	})
+
+	expected := 0
+
+	for i := range bs {
+		for j := i + 1; j < len(bs); j++ {
+
+			if cl.Separation(bs[i].Xs[0], bs[j].Xs[0]).Norm() >= cl.Cutoff() {
+				continue
+			}
+
+			expected++
+			if !found[[2]int{i, j}] {
+
+				t.Errorf("pair (%d, %d) not found", i, j)
+			}
+		}
+	}
+
+	if expected != len(found) {
+
+		t.Errorf("found %d pairs instead of %d", len(found), expected)
+	}
+}
+
+func TestOpenCellList(t *testing.T) {
+
+	bs := randomBodies(300, 10, rand.New(rand.NewSource(1)))
+
+	cellsMatchBruteForce(NewCellList(1.5), bs, t)
+}
+
+func TestPeriodicCellList(t *testing.T) {
+
+	bs := randomBodies(300, 10, rand.New(rand.NewSource(2)))
+
+	cubic := NewOrthorhombic(vect.NewVector(10, 10, 10))
+	slab := NewOrthorhombic(vect.NewVector(10, 5, 0))
+	skewed := NewTriclinic(
+		vect.NewVector(10, 0, 0),
+		vect.NewVector(3, 9, 0),
+		vect.NewVector(-2, 1, 10),
+	)
+
+	cellsMatchBruteForce(NewCellListIn(cubic, 1.5), bs, t)
+	cellsMatchBruteForce(NewCellListIn(slab, 2.5), bs, t)
+	cellsMatchBruteForce(NewCellListIn(skewed, 2), bs, t)
+}
+
+type fixedClock float64
+
+func (c fixedClock) Time() float64 {
+
+	return float64(c)
+}
+
+func TestShearedCellList(t *testing.T) {
+
+	bs := randomBodies(300, 10, rand.New(rand.NewSource(4)))
+
+	le := NewLeesEdwards(vect.NewVector(10, 10, 10), 0.1, fixedClock(13.7))
+
+	cellsMatchBruteForce(NewCellListIn(le, 1.5), bs, t)
+}
+
+func TestNeighbourListRebuildsOnlyAfterMovingHalfTheSkin(t *testing.T) {
+
+	bs := randomBodies(100, 5, rand.New(rand.NewSource(3)))
+
+	nl := NewNeighbourList(1, 0.4)
+
+	if !nl.Update(bs) || nl.Rebuilds() != 1 {
+
+		t.Fatalf("the first update should build the list")
+	}
+
+	bs[7].Xs[0] = bs[7].Xs[0].Plus(vect.UnitX.Scale(0.15))
+
+	if nl.Update(bs) {
+
+		t.Fatalf("moving a body by less than half the skin should not rebuild the list")
+	}
+
+	bs[7].Xs[0] = bs[7].Xs[0].Plus(vect.UnitX.Scale(0.1))
+
+	if !nl.Update(bs) || nl.Rebuilds() != 2 {
+
+		t.Fatalf("moving a body by more than half the skin should rebuild the list")
+	}
+
+	for i := range bs {
+		for j := range bs {
+
+			if i != j && separation(bs, i, j).Norm() < nl.Cutoff() && !contains(nl.Of(i), j) {
+
+				t.Errorf("body %d should be a neighbour of %d", j, i)
+			}
+		}
+	}
+}
+
+func TestNeighbourListRebuildsWhenBodiesAreReplaced(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	for _, x := range []float64{0, 1, 1.05} {
+
+		sys.AddBody(vect.NewVector(x, 0, 0), vect.Zero, 1, 0.005)
+	}
+
+	nl := sys.NeighbourList(2.5)
+	nl.Update(sys.bodies)
+
+	// Each body ends up near where the one with it's index was before
+	sys.RemoveBody(1)
+	sys.AddBody(vect.NewVector(1.05, 0, 0), vect.Zero, 1, 0.005)
+
+	if !nl.Update(sys.bodies) {
+
+		t.Fatalf("replacing a body should rebuild the list")
+	}
+}
+
+func TestShortNeighbourListIsRejected(t *testing.T) {
+
+	bs := randomBodies(10, 3, rand.New(rand.NewSource(5)))
+
+	pf := &PairForce{
+		Potential:  LennardJones{Epsilon: 1, Sigma: 1},
+		Cutoff:     2.5,
+		Neighbours: NewNeighbourList(2, 0.3),
+	}
+
+	defer func() {
+
+		if recover() == nil {
+
+			t.Errorf("a neighbour list shorter than the cutoff should not be used")
+		}
+	}()
+
+	pf.Prepare(bs)
+}
@@ -0,0 +1,233 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A central interaction whose strength only depends on the distance between
+// two bodies
+type PairPotential interface {
+	// Potential energy at separation r
+	Energy(r float64) float64
+	// Derivative of the energy with respect to the separation
+	Derivative(r float64) float64
+}
+
+type shifted struct {
+	PairPotential
+	by float64
+}
+
+// Shifts a potential so that it's energy is zero at rc
+func Shifted(p PairPotential, rc float64) PairPotential {
+
+	return shifted{PairPotential: p, by: p.Energy(rc)}
+}
+
+func (s shifted) Energy(r float64) float64 {
+
+	return s.PairPotential.Energy(r) - s.by
+}
+
+// A force due to a pair potential acting between every two bodies
+//
+// With a cutoff, the interacting pairs are found using a neighbour list or a
+// cell list rebuilt whenever the force is prepared.
+type PairForce struct {
+	Potential PairPotential
+	// When not nil, the potential between two bodies is looked up by their
+	// species in it instead. Bodies of species without a potential between
+	// them do not interact.
+	Pairs SpeciesPairs
+	// Bodies further apart do not interact. Zero means there is no cutoff.
+	Cutoff float64
+	// When not nil, the pairs within the cutoff are looked up in it instead
+	// of a cell list. It's cutoff must not be smaller than the force's, or
+	// Prepare panics.
+	Neighbours *NeighbourList
+
+	cells *CellList
+	nbs   [][]int
+}
+
+// Find the pairs of bodies that are within the cutoff
+func (pf *PairForce) Prepare(bs []*Body) {
+
+	if pf.Cutoff == 0 {
+
+		return
+	}
+
+	if pf.Neighbours != nil {
+
+		pf.nbs = neighboursFor(pf.Neighbours, bs, pf.Cutoff)
+
+		return
+	}
+
+	if pf.cells == nil || pf.cells.Cutoff() != pf.Cutoff || pf.cells.Box() != boxOf(bs) {
+
+		pf.cells = NewCellListIn(boxOf(bs), pf.Cutoff)
+	}
+
+	pf.nbs = pf.cells.Neighbours(bs)
+}
+
+func (pf *PairForce) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	f := vect.Zero
+
+	pf.eachPartner(bs, i, func(j int) {
+
+		dir, r := separation(bs, i, j).UnitAndNorm()
+
+		if p := pf.potential(bs, i, j); p != nil && pf.within(r) {
+
+			f = f.Plus(dir.Scale(p.Derivative(r)))
+		}
+	})
+
+	return f.Scale(1 / bs[i].Mass())
+}
+
+// The potential energy of all the interacting pairs
+func (pf *PairForce) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	if pf.Cutoff == 0 {
+
+		for i := range bs {
+			for j := i + 1; j < len(bs); j++ {
+
+				if p := pf.potential(bs, i, j); p != nil {
+
+					u += p.Energy(separation(bs, i, j).Norm())
+				}
+			}
+		}
+
+		return u
+	}
+
+	cells := NewCellListIn(boxOf(bs), pf.Cutoff)
+	cells.Build(bs)
+
+	cells.EachPair(bs, func(i, j int, d vect.Vector) {
+
+		if p := pf.potential(bs, i, j); p != nil {
+
+			u += p.Energy(d.Norm())
+		}
+	})
+
+	return u
+}
+
+// The virial of all the interacting pairs
+func (pf *PairForce) Virial(bs []*Body) Tensor {
+
+	var w Tensor
+
+	for i := range bs {
+
+		pf.eachPartner(bs, i, func(j int) {
+
+			if j < i {
+				return
+			}
+
+			d := separation(bs, i, j)
+
+			p := pf.potential(bs, i, j)
+
+			if r := d.Norm(); p != nil && pf.within(r) {
+
+				w = w.Plus(pairVirial(d, p.Derivative(r)))
+			}
+		})
+	}
+
+	return w
+}
+
+// Adds the accelerations due to all the interacting pairs, computing each
+// interaction once
+func (pf *PairForce) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	pf.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Adds the accelerations due to the pairs every parts-th body forms with the
+// bodies after it
+func (pf *PairForce) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	for i := part; i < len(bs); i += parts {
+
+		pf.eachPartner(bs, i, func(j int) {
+
+			if j < i {
+				return
+			}
+
+			d := separation(bs, i, j)
+
+			p := pf.potential(bs, i, j)
+
+			if r := d.Norm(); p != nil && pf.within(r) {
+
+				addPairAccels(bs, as, i, j, d, p.Energy(r), p.Derivative(r), tally)
+			}
+		})
+	}
+}
+
+// Calls do with the index of every body that could interact with the i-th
+// one
+func (pf *PairForce) eachPartner(bs []*Body, i int, do func(j int)) {
+
+	if pf.Cutoff > 0 && len(pf.nbs) == len(bs) {
+
+		for _, j := range pf.nbs[i] {
+
+			do(j)
+		}
+
+		return
+	}
+
+	for j := range bs {
+
+		if j != i {
+
+			do(j)
+		}
+	}
+}
+
+// The potential between the i-th and j-th body
+func (pf *PairForce) potential(bs []*Body, i, j int) PairPotential {
+
+	if pf.Pairs == nil {
+
+		return pf.Potential
+	}
+
+	return pf.Pairs.Between(bs[i].SpeciesName(), bs[j].SpeciesName())
+}
+
+func (pf *PairForce) within(r float64) bool {
+
+	return pf.Cutoff == 0 || r < pf.Cutoff
+}
+
+// The minimum-image vector pointing from the latest position of the i-th body
+// to the latest position of the j-th one
+func separation(bs []*Body, i, j int) vect.Vector {
+
+	return Separation(bs[i], bs[j])
+}
@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+)
+
+func derivativeMatchesEnergy(name string, p PairPotential, r float64, t *testing.T) {
+
+	h := 1e-6
+
+	numeric := (p.Energy(r+h) - p.Energy(r-h)) / (2 * h)
+
+	if math.Abs(numeric-p.Derivative(r)) > 1e-5*math.Max(1, math.Abs(numeric)) {
+
+		t.Errorf(
+			"%s.Derivative(%f) should be %f not %f",
+			name, r, numeric, p.Derivative(r),
+		)
+	}
+}
+
+func TestPairPotentialDerivatives(t *testing.T) {
+
+	potentials := map[string]PairPotential{
+		"Harmonic":     Harmonic{K: 2, R0: 1},
+		"LennardJones": LennardJones{Epsilon: 1, Sigma: 1},
+		"Morse":        Morse{D: 1.5, A: 2, R0: 1.1},
+		"Buckingham":   Buckingham{A: 1000, Rho: 0.3, C: 2},
+		"FENE":         FENE{K: 30, R0: 1.5},
+		"WCA":          WCA(1, 1).Potential,
+	}
+
+	for name, p := range potentials {
+		for _, r := range []float64{0.9, 1, 1.2, 1.4} {
+
+			derivativeMatchesEnergy(name, p, r, t)
+		}
+	}
+}
+
+func TestWCAVanishesAtCutoff(t *testing.T) {
+
+	wca := WCA(1, 1)
+
+	if u := wca.Potential.Energy(wca.Cutoff); math.Abs(u) > 1e-12 {
+
+		t.Fatalf("WCA energy at the cutoff should be 0 not %g", u)
+	}
+
+	if d := wca.Potential.Derivative(wca.Cutoff); math.Abs(d) > 1e-12 {
+
+		t.Fatalf("WCA force at the cutoff should be 0 not %g", d)
+	}
+}
+
+func TestFENEBreaksAtMaxLength(t *testing.T) {
+
+	fene := FENE{K: 30, R0: 1.5}
+
+	if !math.IsInf(fene.Energy(fene.R0), 1) {
+
+		t.Fatalf("FENE energy at R0 should be infinite not %g", fene.Energy(fene.R0))
+	}
+}
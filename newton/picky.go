@@ -5,6 +5,8 @@
 package newton
 
 import (
+	"sync"
+
 	"github.com/szabba/md/vect"
 )
 
@@ -12,6 +14,14 @@ import (
 type PickyForce struct {
 	force   Force
 	zeroFor []int
+	ignored Selection
+
+	// Which bodies are ignored, as of the last time the force was prepared,
+	// and the accelerations due to the underlying force for each part of a
+	// pass
+	skip []bool
+	mu   sync.Mutex
+	bufs [][]vect.Vector
 }
 
 // Creates a picky version of a force
@@ -20,8 +30,32 @@ func NewPicky(f Force, zeroFor ...int) Force {
 	return &PickyForce{force: f, zeroFor: zeroFor}
 }
 
+// Creates a version of a force that doesn't affect the selected bodies
+func NewPickyIgnoring(f Force, ignored Selection) Force {
+
+	return &PickyForce{force: f, ignored: ignored}
+}
+
+// Creates a version of a force that doesn't affect bodies of the named species
+func NewPickySpecies(f Force, species ...string) Force {
+
+	return NewPickyIgnoring(f, OfSpecies(species...))
+}
+
+// Creates a version of a force that doesn't affect the bodies with the given
+// IDs
+func NewPickyIDs(f Force, ids ...int) Force {
+
+	return NewPickyIgnoring(f, WithIDs(ids...))
+}
+
 func (picky *PickyForce) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
 
+	if picky.ignored != nil && picky.ignored.Selects(bs, i) {
+
+		return vect.Zero
+	}
+
 	for _, ignored := range picky.zeroFor {
 
 		if ignored == i {
@@ -32,3 +66,116 @@ func (picky *PickyForce) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
 
 	return picky.force.Accel(bs, i, dt)
 }
+
+// Prepare the underlying force and find the ignored bodies
+func (picky *PickyForce) Prepare(bs []*Body) {
+
+	prepare(picky.force, bs)
+
+	picky.findSkipped(bs)
+}
+
+// Marks which of the bodies are ignored
+func (picky *PickyForce) findSkipped(bs []*Body) {
+
+	if cap(picky.skip) < len(bs) {
+
+		picky.skip = make([]bool, len(bs))
+	}
+	picky.skip = picky.skip[:len(bs)]
+
+	for i := range bs {
+
+		picky.skip[i] = picky.ignored != nil && picky.ignored.Selects(bs, i)
+	}
+
+	for _, i := range picky.zeroFor {
+
+		if i < len(bs) {
+
+			picky.skip[i] = true
+		}
+	}
+}
+
+// Follows a body being added. The new body is not ignored.
+func (picky *PickyForce) BodyAdded(i int) {
+
+	for k, j := range picky.zeroFor {
+
+		picky.zeroFor[k] = indexAfterAdding(j, i)
+	}
+
+	bodyAdded(picky.force, i)
+}
+
+// Follows a body being removed, forgetting about it if it was ignored
+func (picky *PickyForce) BodyRemoved(i int) {
+
+	kept := picky.zeroFor[:0]
+
+	for _, j := range picky.zeroFor {
+
+		if j != i {
+
+			kept = append(kept, indexAfterRemoving(j, i))
+		}
+	}
+
+	picky.zeroFor = kept
+
+	bodyRemoved(picky.force, i)
+}
+
+// Adds the accelerations due to the underlying force, except for the ignored
+// bodies. The tally gets the energy and virial of the underlying force, with
+// the ignored bodies included.
+func (picky *PickyForce) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	picky.findSkipped(bs)
+
+	picky.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Adds a share of the accelerations due to the underlying force, except for
+// the bodies that were ignored when the force was prepared
+func (picky *PickyForce) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	all := picky.buffer(part, len(bs))
+
+	addAccelsPart(picky.force, bs, all, part, parts, dt, tally)
+
+	for i, a := range all {
+
+		if !picky.skip[i] {
+
+			as[i] = as[i].Plus(a)
+		}
+	}
+}
+
+// A zeroed buffer for n accelerations, kept for the given part of a pass
+func (picky *PickyForce) buffer(part, n int) []vect.Vector {
+
+	picky.mu.Lock()
+
+	for len(picky.bufs) <= part {
+
+		picky.bufs = append(picky.bufs, nil)
+	}
+
+	if cap(picky.bufs[part]) < n {
+
+		picky.bufs[part] = make([]vect.Vector, n)
+	}
+	buf := picky.bufs[part][:n]
+
+	picky.mu.Unlock()
+
+	for i := range buf {
+
+		buf[i] = vect.Zero
+	}
+
+	return buf
+}
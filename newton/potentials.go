@@ -0,0 +1,122 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+)
+
+// A harmonic potential with it's minimum at R0
+type Harmonic struct {
+	K, R0 float64
+}
+
+func (h Harmonic) Energy(r float64) float64 {
+
+	return h.K * math.Pow(r-h.R0, 2) / 2
+}
+
+func (h Harmonic) Derivative(r float64) float64 {
+
+	return h.K * (r - h.R0)
+}
+
+// The 12-6 Lennard-Jones potential
+type LennardJones struct {
+	Epsilon, Sigma float64
+}
+
+func (lj LennardJones) Energy(r float64) float64 {
+
+	s6 := math.Pow(lj.Sigma/r, 6)
+
+	return 4 * lj.Epsilon * (s6*s6 - s6)
+}
+
+func (lj LennardJones) Derivative(r float64) float64 {
+
+	s6 := math.Pow(lj.Sigma/r, 6)
+
+	return 24 * lj.Epsilon * (s6 - 2*s6*s6) / r
+}
+
+// The purely repulsive Weeks-Chandler-Andersen force -- a Lennard-Jones
+// potential cut off at it's minimum and shifted up to zero there
+func WCA(epsilon, sigma float64) *PairForce {
+
+	rc := math.Pow(2, 1./6) * sigma
+
+	return &PairForce{
+		Potential: Shifted(LennardJones{Epsilon: epsilon, Sigma: sigma}, rc),
+		Cutoff:    rc,
+	}
+}
+
+// The Morse potential with a well of depth D at R0 and a width controlled by
+// A
+//
+// The energy goes to zero at infinity.
+type Morse struct {
+	D, A, R0 float64
+}
+
+func (m Morse) Energy(r float64) float64 {
+
+	e := math.Exp(-m.A * (r - m.R0))
+
+	return m.D * (e*e - 2*e)
+}
+
+func (m Morse) Derivative(r float64) float64 {
+
+	e := math.Exp(-m.A * (r - m.R0))
+
+	return 2 * m.D * m.A * (e - e*e)
+}
+
+// The Buckingham exp-6 potential
+//
+//	U(r) = A exp(-r/Rho) - C/r^6
+type Buckingham struct {
+	A, Rho, C float64
+}
+
+func (b Buckingham) Energy(r float64) float64 {
+
+	return b.A*math.Exp(-r/b.Rho) - b.C/math.Pow(r, 6)
+}
+
+func (b Buckingham) Derivative(r float64) float64 {
+
+	return -b.A/b.Rho*math.Exp(-r/b.Rho) + 6*b.C/math.Pow(r, 7)
+}
+
+// A finitely extensible nonlinear elastic spring, that can't be stretched
+// beyond R0
+//
+// The energy and it's derivative are infinite at R0 and beyond.
+type FENE struct {
+	K, R0 float64
+}
+
+func (f FENE) Energy(r float64) float64 {
+
+	if r >= f.R0 {
+
+		return math.Inf(1)
+	}
+
+	return -f.K * f.R0 * f.R0 / 2 * math.Log(1-math.Pow(r/f.R0, 2))
+}
+
+func (f FENE) Derivative(r float64) float64 {
+
+	if r >= f.R0 {
+
+		return math.Inf(1)
+	}
+
+	return f.K * r / (1 - math.Pow(r/f.R0, 2))
+}
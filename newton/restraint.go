@@ -0,0 +1,119 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A harmonic spring of stiffness K tying the I-th body to a fixed point
+type Restraint struct {
+	I     int
+	Point vect.Vector
+	K     float64
+}
+
+// A force due to a set of position restraints
+//
+// The displacement of a body from it's reference point follows the
+// minimum-image convention in a periodic box.
+type Restraints []Restraint
+
+func (rs Restraints) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	f := vect.Zero
+
+	for _, r := range rs {
+
+		if r.I == i {
+
+			f = f.Plus(r.force(bs))
+		}
+	}
+
+	return f.Scale(1 / bs[i].Mass())
+}
+
+// The energy stored in all the restraints
+func (rs Restraints) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for _, r := range rs {
+
+		d := r.displacement(bs)
+
+		u += r.K * d.Dot(d) / 2
+	}
+
+	return u
+}
+
+// Adds the accelerations due to all the restraints, going over each of them
+// once
+func (rs Restraints) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	rs.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Adds the accelerations due to every parts-th restraint
+func (rs Restraints) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	for k := part; k < len(rs); k += parts {
+
+		r := rs[k]
+
+		as[r.I] = as[r.I].Plus(r.force(bs).Scale(1 / bs[r.I].Mass()))
+
+		if tally != nil {
+
+			d := r.displacement(bs)
+			tally.Energy += r.K * d.Dot(d) / 2
+		}
+	}
+}
+
+// Follows a body being added
+//
+// Only *Restraints can follow the bodies of a System.
+func (rs *Restraints) BodyAdded(i int) {
+
+	for k := range *rs {
+
+		(*rs)[k].I = indexAfterAdding((*rs)[k].I, i)
+	}
+}
+
+// Follows a body being removed, dropping it's restraints
+//
+// Only *Restraints can follow the bodies of a System.
+func (rs *Restraints) BodyRemoved(i int) {
+
+	kept := (*rs)[:0]
+
+	for _, r := range *rs {
+
+		if r.I != i {
+
+			r.I = indexAfterRemoving(r.I, i)
+			kept = append(kept, r)
+		}
+	}
+
+	*rs = kept
+}
+
+// The vector from the reference point to the restrained body
+func (r Restraint) displacement(bs []*Body) vect.Vector {
+
+	b := bs[r.I]
+
+	return b.box.Separation(r.Point, b.XLatest())
+}
+
+func (r Restraint) force(bs []*Body) vect.Vector {
+
+	return r.displacement(bs).Scale(-r.K)
+}
@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// A seedable source of independent random number streams
+//
+// The generator is counter-based: the numbers of a stream are a hash of the
+// seed, the keys identifying the stream and the position in it. Streams
+// keyed by eg. a body's ID and the step index give the same numbers however
+// the bodies are split among workers, and need no state to be saved besides
+// the seed. A stream from Next kept across steps is another matter -- it's
+// position has to be saved with it's MarshalBinary to continue it after a
+// restart.
+type RNG struct {
+	seed uint64
+	// The number of streams handed out by Next
+	handed uint64
+}
+
+// Constructs a generator with the given seed
+func NewRNG(seed uint64) *RNG {
+
+	return &RNG{seed: seed}
+}
+
+// Start the generator anew with the given seed
+func (rng *RNG) Seed(seed uint64) {
+
+	rng.seed, rng.handed = seed, 0
+}
+
+// The stream identified by the keys. The same keys always give the same
+// stream.
+func (rng *RNG) Stream(keys ...uint64) *Stream {
+
+	key := mix(rng.seed ^ 0x6a09e667f3bcc909)
+
+	for _, k := range keys {
+
+		key = mix(key ^ mix(k+0x9e3779b97f4a7c15))
+	}
+
+	return &Stream{key: key}
+}
+
+// A stream different from all the ones handed out by Next before. Calls to
+// Next are part of the generator's state, saved in checkpoints, but the
+// position in the stream is not. Whatever keeps the stream across steps has
+// to save it too, eg. in it's own checkpoint section.
+func (rng *RNG) Next() *Stream {
+
+	rng.handed++
+
+	return rng.Stream(streamNext, rng.handed)
+}
+
+// Keys that set apart the streams used for different purposes
+const (
+	streamNext uint64 = iota + 1<<32
+	streamBody
+)
+
+func (rng *RNG) Section() string {
+
+	return "rng"
+}
+
+func (rng *RNG) MarshalBinary() ([]byte, error) {
+
+	data := make([]byte, 16)
+
+	binary.LittleEndian.PutUint64(data, rng.seed)
+	binary.LittleEndian.PutUint64(data[8:], rng.handed)
+
+	return data, nil
+}
+
+func (rng *RNG) UnmarshalBinary(data []byte) error {
+
+	if len(data) != 16 {
+
+		return errors.New("rng: invalid state")
+	}
+
+	rng.seed = binary.LittleEndian.Uint64(data)
+	rng.handed = binary.LittleEndian.Uint64(data[8:])
+
+	return nil
+}
+
+// A stream of random numbers
+//
+// It is a math/rand.Source64, so a rand.Rand can be built on top of it.
+type Stream struct {
+	key, counter uint64
+
+	// The second of a pair of normally distributed numbers, when there is one
+	// left
+	normal    float64
+	hasNormal bool
+}
+
+func (s *Stream) Uint64() uint64 {
+
+	s.counter++
+
+	return mix(mix(s.key+s.counter*0x9e3779b97f4a7c15) ^ s.key)
+}
+
+func (s *Stream) Int63() int64 {
+
+	return int64(s.Uint64() >> 1)
+}
+
+// Restart the stream from a key derived from the seed
+func (s *Stream) Seed(seed int64) {
+
+	*s = Stream{key: mix(uint64(seed))}
+}
+
+// The position in the stream, so that it can be saved and continued
+func (s *Stream) MarshalBinary() ([]byte, error) {
+
+	data := make([]byte, 25)
+
+	binary.LittleEndian.PutUint64(data, s.key)
+	binary.LittleEndian.PutUint64(data[8:], s.counter)
+	binary.LittleEndian.PutUint64(data[16:], math.Float64bits(s.normal))
+
+	if s.hasNormal {
+
+		data[24] = 1
+	}
+
+	return data, nil
+}
+
+func (s *Stream) UnmarshalBinary(data []byte) error {
+
+	if len(data) != 25 || data[24] > 1 {
+
+		return errors.New("rng: invalid stream state")
+	}
+
+	s.key = binary.LittleEndian.Uint64(data)
+	s.counter = binary.LittleEndian.Uint64(data[8:])
+	s.normal = math.Float64frombits(binary.LittleEndian.Uint64(data[16:]))
+	s.hasNormal = data[24] == 1
+
+	return nil
+}
+
+// A number uniformly distributed in [0, 1)
+func (s *Stream) Float64() float64 {
+
+	return float64(s.Uint64()>>11) / (1 << 53)
+}
+
+// A normally distributed number with zero mean and unit variance
+func (s *Stream) NormFloat64() float64 {
+
+	if s.hasNormal {
+
+		s.hasNormal = false
+
+		return s.normal
+	}
+
+	// Box-Muller, with 1 - u1 in (0, 1]
+	u1, u2 := 1-s.Float64(), s.Float64()
+
+	r := math.Sqrt(-2 * math.Log(u1))
+	sin, cos := math.Sincos(2 * math.Pi * u2)
+
+	s.normal, s.hasNormal = r*sin, true
+
+	return r * cos
+}
+
+// The finalizer of the SplitMix64 generator, a bijective mixing of the bits
+func mix(z uint64) uint64 {
+
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,151 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestStreamsAreReproducible(t *testing.T) {
+
+	a, b := NewRNG(7).Stream(1, 2), NewRNG(7).Stream(1, 2)
+	c := NewRNG(7).Stream(2, 1)
+
+	same := 0
+	for i := 0; i < 100; i++ {
+
+		x, y, z := a.Uint64(), b.Uint64(), c.Uint64()
+
+		if x != y {
+
+			t.Fatalf("streams with the same keys should agree, but gave %d and %d", x, y)
+		}
+
+		if x == z {
+
+			same++
+		}
+	}
+
+	if same > 0 {
+
+		t.Errorf("streams with different keys should differ, but agreed %d times", same)
+	}
+}
+
+func TestStreamDistributions(t *testing.T) {
+
+	s := NewRNG(1).Stream(0)
+
+	n := 100000
+	var sum, sumNormal, sumSquares float64
+
+	for i := 0; i < n; i++ {
+
+		sum += s.Float64()
+
+		x := s.NormFloat64()
+		sumNormal += x
+		sumSquares += x * x
+	}
+
+	if mean := sum / float64(n); math.Abs(mean-0.5) > 0.01 {
+
+		t.Errorf("the mean of uniform numbers should be near 0.5, not %g", mean)
+	}
+
+	mean, variance := sumNormal/float64(n), sumSquares/float64(n)
+
+	if math.Abs(mean) > 0.02 || math.Abs(variance-1) > 0.02 {
+
+		t.Errorf("normal numbers should have mean 0 and variance 1, not %g and %g", mean, variance)
+	}
+}
+
+func TestBodyStreamsDoNotDependOnWorkers(t *testing.T) {
+
+	serial, parallel := ljGas(27, 1), ljGas(27, 1)
+	parallel.SetParallel(4)
+
+	serial.RNG().Seed(9)
+	parallel.RNG().Seed(9)
+
+	draw := func(sys *System) []float64 {
+
+		xs := make([]float64, sys.Bodies())
+
+		sys.forEachBody(func(i int) {
+
+			xs[i] = sys.BodyStream(i, 0).Float64()
+		})
+
+		return xs
+	}
+
+	a, b := draw(serial), draw(parallel)
+
+	for i := range a {
+
+		if a[i] != b[i] {
+
+			t.Fatalf("body %d should draw the same number with any number of workers", i)
+		}
+	}
+}
+
+func TestRNGSurvivesCheckpoints(t *testing.T) {
+
+	sys := ljGas(8, 1)
+	sys.RNG().Seed(3)
+	sys.RNG().Next()
+
+	var buf bytes.Buffer
+	if err := sys.WriteCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	restarted := ljGas(8, 1)
+	if err := restarted.ReadCheckpoint(&buf); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if x, y := sys.RNG().Next().Uint64(), restarted.RNG().Next().Uint64(); x != y {
+
+		t.Errorf("the restored generator should continue with %d, not %d", x, y)
+	}
+}
+
+func TestStreamsCanBeContinued(t *testing.T) {
+
+	s := NewRNG(5).Next()
+
+	// Leave half of a pair of normal numbers in the stream
+	s.Uint64()
+	s.NormFloat64()
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+
+		t.Fatal(err)
+	}
+
+	restored := new(Stream)
+	if err := restored.UnmarshalBinary(data); err != nil {
+
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+
+		if x, y := s.NormFloat64(), restored.NormFloat64(); x != y {
+
+			t.Fatalf("the restored stream should continue with %g, not %g", x, y)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"context"
+)
+
+// Something that looks at a system as it runs, eg. to write it out
+type Observer interface {
+	Observe(v View)
+}
+
+// An observer that is a plain function
+type ObserverFunc func(v View)
+
+func (f ObserverFunc) Observe(v View) {
+
+	f(v)
+}
+
+// A point within a step at which observers can be called
+type Event int
+
+const (
+	// Before the accelerations are computed, at the start of a step
+	BeforeForces Event = iota
+	// After the accelerations are computed, before the bodies are moved
+	AfterForces
+	// At the end of a step
+	AfterStep
+)
+
+// An observer called at some event every few steps
+type periodic struct {
+	at    Event
+	every int
+	Observer
+}
+
+// Have the observer look at the system after every given number of steps.
+// When every is not positive, it is called after each one.
+//
+// Observers are called in the order they were added, when the step index is a
+// multiple of every. A run starting at step zero also shows them the initial
+// state.
+func (sys *System) AddObserver(every int, o Observer) {
+
+	sys.AddObserverAt(AfterStep, every, o)
+}
+
+// Have the observer look at the system at the given event, every given
+// number of steps. Observers of events within a step see the index of the
+// step in progress.
+func (sys *System) AddObserverAt(at Event, every int, o Observer) {
+
+	if every < 1 {
+
+		every = 1
+	}
+
+	sys.observers = append(
+		sys.observers, periodic{at: at, every: every, Observer: o},
+	)
+}
+
+// The number of steps performed so far
+func (sys *System) StepIndex() int {
+
+	return sys.step
+}
+
+// Perform the given number of steps of size dt
+//
+// When the system is at step zero, the observers of AfterStep also see the
+// initial state before the first step.
+//
+// The run stops early when the context is cancelled, returning it's error.
+func (sys *System) Run(ctx context.Context, dt float64, steps int) error {
+
+	if sys.step == 0 {
+
+		sys.observe(AfterStep)
+	}
+
+	for i := 0; i < steps; i++ {
+
+		if err := ctx.Err(); err != nil {
+
+			return err
+		}
+
+		sys.Step(dt)
+	}
+
+	return nil
+}
+
+// Perform steps of size dt until the simulation time reaches t. The last step
+// ends within half a step of t.
+//
+// The run stops early when the context is cancelled, returning it's error.
+func (sys *System) RunUntil(ctx context.Context, dt, t float64) error {
+
+	steps := 0
+	for end := sys.t; end+dt/2 < t; end += dt {
+
+		steps++
+	}
+
+	return sys.Run(ctx, dt, steps)
+}
+
+// Call the observers of the event due at the current step
+func (sys *System) observe(at Event) {
+
+	for _, o := range sys.observers {
+
+		if o.at == at && sys.step%o.every == 0 {
+
+			o.Observe(View{sys: sys, at: at})
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestRunCallsObservers(t *testing.T) {
+
+	sys := ljGas(8, 1)
+
+	var seen []int
+	sys.AddObserver(3, ObserverFunc(func(v View) {
+
+		seen = append(seen, v.StepIndex())
+	}))
+
+	if err := sys.Run(context.Background(), 0.001, 10); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if want := []int{0, 3, 6, 9}; !reflect.DeepEqual(seen, want) {
+
+		t.Errorf("the observer should see steps %v not %v", want, seen)
+	}
+}
+
+func TestRunUntil(t *testing.T) {
+
+	sys := ljGas(8, 1)
+
+	if err := sys.RunUntil(context.Background(), 0.001, 0.1); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if sys.StepIndex() != 100 || math.Abs(sys.Time()-0.1) > 1e-9 {
+
+		t.Errorf("the run should end at step 100 and time 0.1, not %d and %g", sys.StepIndex(), sys.Time())
+	}
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+
+	sys := ljGas(8, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sys.AddObserver(5, ObserverFunc(func(_ View) { cancel() }))
+
+	if err := sys.Run(ctx, 0.001, 100); err != context.Canceled {
+
+		t.Fatalf("the run should be cancelled, not end with %v", err)
+	}
+
+	if sys.StepIndex() != 0 {
+
+		t.Errorf("the run should stop before the first step, not after %d", sys.StepIndex())
+	}
+}
+
+func TestObserverEvents(t *testing.T) {
+
+	sys := ljGas(8, 1)
+
+	var seen []Event
+	for _, at := range []Event{AfterStep, AfterForces, BeforeForces} {
+
+		sys.AddObserverAt(at, 2, ObserverFunc(func(v View) {
+
+			seen = append(seen, v.Event())
+		}))
+	}
+
+	sys.Step(0.001)
+	sys.Step(0.001)
+
+	if want := []Event{BeforeForces, AfterForces, AfterStep}; !reflect.DeepEqual(seen, want) {
+
+		t.Errorf("the observers should see %v not %v", want, seen)
+	}
+}
+
+func TestViewEnergiesAreConserved(t *testing.T) {
+
+	// The gas is set up for steps of 0.005
+	sys := ljGas(27, 2)
+
+	var energies []float64
+	sys.AddObserver(10, ObserverFunc(func(v View) {
+
+		energies = append(energies, v.KineticEnergy()+v.PotentialEnergy())
+	}))
+
+	if err := sys.Run(context.Background(), 0.005, 100); err != nil {
+
+		t.Fatal(err)
+	}
+
+	for _, e := range energies {
+
+		if math.Abs(e-energies[0]) > 1e-2*math.Abs(energies[0]) {
+
+			t.Fatalf("the total energy should stay near %g, not reach %g", energies[0], e)
+		}
+	}
+}
+
+// A potential that counts how many times it was prepared
+type preparedCount struct {
+	Restraints
+	prepared int
+}
+
+func (p *preparedCount) Prepare(bs []*Body) {
+
+	p.prepared++
+}
+
+func TestViewPotentialEnergyDoesNotPrepare(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	sys.AddBody(vect.UnitX, vect.Zero, 1, 0.1)
+
+	p := &preparedCount{Restraints: Restraints{{I: 0, K: 2}}}
+	sys.SetForce(p)
+
+	var energies []float64
+	sys.AddObserver(1, ObserverFunc(func(v View) {
+
+		energies = append(energies, v.PotentialEnergy())
+	}))
+
+	if err := sys.Run(context.Background(), 0.1, 3); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if energies[0] != 1 {
+
+		t.Errorf("the potential energy should start at 1, not %g", energies[0])
+	}
+
+	if p.prepared != 3 {
+
+		t.Errorf("the force should only be prepared once a step, not %d times in 3", p.prepared)
+	}
+}
@@ -0,0 +1,229 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A group of bodies
+//
+// Regions select bodies by their latest positions, at which forces are
+// evaluated.
+type Selection interface {
+	// Whether the i-th body belongs to the group
+	Selects(bs []*Body, i int) bool
+}
+
+// The indices of the selected bodies
+func Select(s Selection, bs []*Body) []int {
+
+	var is []int
+
+	for i := range bs {
+
+		if s.Selects(bs, i) {
+
+			is = append(is, i)
+		}
+	}
+
+	return is
+}
+
+// The number of selected bodies
+func Count(s Selection, bs []*Body) int {
+
+	n := 0
+
+	for i := range bs {
+
+		if s.Selects(bs, i) {
+
+			n++
+		}
+	}
+
+	return n
+}
+
+// Selects bodies for which the function returns true
+type Where func(b *Body) bool
+
+func (w Where) Selects(bs []*Body, i int) bool {
+
+	return w(bs[i])
+}
+
+// Selects every body
+var All Selection = Where(func(_ *Body) bool { return true })
+
+// Selects no bodies
+var None Selection = Not(All)
+
+// Selects the bodies with indices from Lo to Hi, inclusive
+type IndexRange struct {
+	Lo, Hi int
+}
+
+func (r IndexRange) Selects(_ []*Body, i int) bool {
+
+	return r.Lo <= i && i <= r.Hi
+}
+
+// Selects the bodies at the given indices
+type Indices []int
+
+func (is Indices) Selects(_ []*Body, i int) bool {
+
+	for _, j := range is {
+
+		if i == j {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// Selects the bodies of the named species
+func OfSpecies(names ...string) Selection {
+
+	return Where(func(b *Body) bool {
+
+		for _, name := range names {
+
+			if b.SpeciesName() == name {
+
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Selects the bodies with the given IDs
+func WithIDs(ids ...int) Selection {
+
+	return Where(func(b *Body) bool {
+
+		for _, id := range ids {
+
+			if b.ID() == id {
+
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Selects the bodies within a distance from a point, taking the nearest image
+// of the point in a periodic box
+type Sphere struct {
+	Center vect.Vector
+	Radius float64
+}
+
+func (s Sphere) Selects(bs []*Body, i int) bool {
+
+	b := bs[i]
+
+	return b.box.Separation(s.Center, b.XLatest()).Norm() <= s.Radius
+}
+
+// Selects the bodies inside an axis aligned block with the given corners
+type Block struct {
+	Min, Max vect.Vector
+}
+
+func (blk Block) Selects(bs []*Body, i int) bool {
+
+	x, lo, hi := components(bs[i].XLatest()), components(blk.Min), components(blk.Max)
+
+	for k := range x {
+
+		if x[k] < lo[k] || x[k] > hi[k] {
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// Selects the bodies whose coordinate along an axis (0 for x, 1 for y, 2 for
+// z) is between Lo and Hi
+type Slab struct {
+	Axis   int
+	Lo, Hi float64
+}
+
+func (s Slab) Selects(bs []*Body, i int) bool {
+
+	x := components(bs[i].XLatest())[s.Axis]
+
+	return s.Lo <= x && x <= s.Hi
+}
+
+type and []Selection
+
+// Selects the bodies selected by all of the selections
+func And(ss ...Selection) Selection {
+
+	return and(ss)
+}
+
+func (ss and) Selects(bs []*Body, i int) bool {
+
+	for _, s := range ss {
+
+		if !s.Selects(bs, i) {
+
+			return false
+		}
+	}
+
+	return true
+}
+
+type or []Selection
+
+// Selects the bodies selected by any of the selections
+func Or(ss ...Selection) Selection {
+
+	return or(ss)
+}
+
+func (ss or) Selects(bs []*Body, i int) bool {
+
+	for _, s := range ss {
+
+		if s.Selects(bs, i) {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+type not struct {
+	Selection
+}
+
+// Selects the bodies the selection does not
+func Not(s Selection) Selection {
+
+	return not{s}
+}
+
+func (n not) Selects(bs []*Body, i int) bool {
+
+	return !n.Selection.Selects(bs, i)
+}
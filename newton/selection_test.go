@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+// Five bodies along the x axis, alternately of species A and B
+func selectionBodies() *System {
+
+	a, b := &Species{Name: "A", Mass: 1}, &Species{Name: "B", Mass: 2}
+
+	sys := NewSystem(Verlet, 0)
+
+	for i := 0; i < 5; i++ {
+
+		body := sys.AddBody(vect.NewVector(float64(i), 0, 0), vect.Zero, 1, 0.1)
+
+		if i%2 == 0 {
+
+			body.SetSpecies(a)
+
+		} else {
+
+			body.SetSpecies(b)
+		}
+	}
+
+	return sys
+}
+
+func TestParseSelection(t *testing.T) {
+
+	sys := selectionBodies()
+
+	selections := map[string][]int{
+		"all":                                   {0, 1, 2, 3, 4},
+		"none":                                  nil,
+		"index 1 to 3":                          {1, 2, 3},
+		"id 4":                                  {4},
+		"species A":                             {0, 2, 4},
+		"species A and within 2 of 0,0,0":       {0, 2},
+		"not species A or index 0":              {0, 1, 3},
+		"not (species A or index 1)":            {3},
+		"block -1,-1,-1 to 1.5,1,1":             {0, 1},
+		"x from 2 to 10 and not species B":      {2, 4},
+		"species B and (id 1 or x from 3 to 3)": {1, 3},
+	}
+
+	for text, want := range selections {
+
+		s, err := ParseSelection(text)
+		if err != nil {
+
+			t.Errorf("%q: %s", text, err)
+			continue
+		}
+
+		if got := sys.Select(s); !reflect.DeepEqual(got, want) {
+
+			t.Errorf("%q should select %v not %v", text, want, got)
+		}
+	}
+}
+
+func TestParseSelectionErrors(t *testing.T) {
+
+	for _, text := range []string{
+		"", "species", "index x", "within 2 of 0,0", "(all", "all all", "all and",
+	} {
+
+		if _, err := ParseSelection(text); err == nil {
+
+			t.Errorf("%q should not parse", text)
+		}
+	}
+}
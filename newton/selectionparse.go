@@ -0,0 +1,309 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/szabba/md/vect"
+)
+
+// Parses a textual selection, eg.
+//
+//	species A and within 2 of 0,0,0
+//	not (index 0 to 9 or id 42)
+//
+// The selections it understands are
+//
+//	all, none
+//	index I [to J]         indices from I to J, inclusive
+//	id I [to J]            IDs from I to J, inclusive
+//	species NAME...        bodies of any of the named species
+//	within R of X,Y,Z      a sphere
+//	block X,Y,Z to X,Y,Z   an axis aligned block
+//	x|y|z from LO to HI    a slab
+//
+// combined with not, and, or (from the tightest binding to the loosest) and
+// parentheses. Vectors are written without spaces.
+func ParseSelection(text string) (Selection, error) {
+
+	text = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(text)
+
+	p := &selectionParser{words: strings.Fields(text)}
+
+	s := p.or()
+
+	if p.err == nil && p.at < len(p.words) {
+
+		p.fail("the end of the selection")
+	}
+
+	if p.err != nil {
+
+		return nil, p.err
+	}
+
+	return s, nil
+}
+
+// A recursive descent parser of selections. Once it fails, it keeps the first
+// error and parses nothing more.
+type selectionParser struct {
+	words []string
+	at    int
+	err   error
+}
+
+func (p *selectionParser) or() Selection {
+
+	ss := []Selection{p.and()}
+
+	for p.accept("or") {
+
+		ss = append(ss, p.and())
+	}
+
+	if len(ss) == 1 {
+
+		return ss[0]
+	}
+
+	return Or(ss...)
+}
+
+func (p *selectionParser) and() Selection {
+
+	ss := []Selection{p.factor()}
+
+	for p.accept("and") {
+
+		ss = append(ss, p.factor())
+	}
+
+	if len(ss) == 1 {
+
+		return ss[0]
+	}
+
+	return And(ss...)
+}
+
+func (p *selectionParser) factor() Selection {
+
+	switch {
+
+	case p.accept("not"):
+
+		return Not(p.factor())
+
+	case p.accept("("):
+
+		s := p.or()
+		p.expect(")")
+
+		return s
+	}
+
+	return p.atom()
+}
+
+func (p *selectionParser) atom() Selection {
+
+	switch word := p.next(); word {
+
+	case "all":
+		return All
+
+	case "none":
+		return None
+
+	case "index":
+		lo, hi := p.intRange()
+
+		return IndexRange{Lo: lo, Hi: hi}
+
+	case "id":
+		lo, hi := p.intRange()
+
+		return Where(func(b *Body) bool { return lo <= b.ID() && b.ID() <= hi })
+
+	case "species":
+		names := []string{p.name()}
+
+		for p.at < len(p.words) && !isSelectionKeyword(p.words[p.at]) {
+
+			names = append(names, p.next())
+		}
+
+		return OfSpecies(names...)
+
+	case "within":
+		r := p.number()
+		p.expect("of")
+
+		return Sphere{Center: p.vector(), Radius: r}
+
+	case "block":
+		min := p.vector()
+		p.expect("to")
+
+		return Block{Min: min, Max: p.vector()}
+
+	case "x", "y", "z":
+		axis := int(word[0] - 'x')
+
+		p.expect("from")
+		lo := p.number()
+		p.expect("to")
+
+		return Slab{Axis: axis, Lo: lo, Hi: p.number()}
+	}
+
+	p.at--
+	p.fail("a selection")
+
+	return None
+}
+
+// Parses I [to J], where J is I when missing
+func (p *selectionParser) intRange() (lo, hi int) {
+
+	lo = p.integer()
+
+	if p.accept("to") {
+
+		return lo, p.integer()
+	}
+
+	return lo, lo
+}
+
+func (p *selectionParser) integer() int {
+
+	n, err := strconv.Atoi(p.next())
+	if err != nil {
+
+		p.at--
+		p.fail("an integer")
+	}
+
+	return n
+}
+
+func (p *selectionParser) number() float64 {
+
+	x, err := strconv.ParseFloat(p.next(), 64)
+	if err != nil {
+
+		p.at--
+		p.fail("a number")
+	}
+
+	return x
+}
+
+func (p *selectionParser) vector() vect.Vector {
+
+	parts := strings.Split(p.next(), ",")
+
+	var x [3]float64
+	for k := range x {
+
+		var err error
+
+		if len(parts) != len(x) {
+
+			err = fmt.Errorf("%d components", len(parts))
+
+		} else {
+
+			x[k], err = strconv.ParseFloat(parts[k], 64)
+		}
+
+		if err != nil {
+
+			p.at--
+			p.fail("a vector X,Y,Z")
+
+			break
+		}
+	}
+
+	return fromComponents(x)
+}
+
+func (p *selectionParser) name() string {
+
+	if p.at >= len(p.words) || isSelectionKeyword(p.words[p.at]) {
+
+		p.fail("a name")
+	}
+
+	return p.next()
+}
+
+// Moves past the next word if it is the given one
+func (p *selectionParser) accept(word string) bool {
+
+	if p.err == nil && p.at < len(p.words) && p.words[p.at] == word {
+
+		p.at++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *selectionParser) expect(word string) {
+
+	if !p.accept(word) {
+
+		p.fail(strconv.Quote(word))
+	}
+}
+
+// The next word, or an empty string at the end of the text or after an error
+func (p *selectionParser) next() string {
+
+	if p.err != nil || p.at >= len(p.words) {
+
+		p.at++
+
+		return ""
+	}
+
+	p.at++
+
+	return p.words[p.at-1]
+}
+
+func (p *selectionParser) fail(expected string) {
+
+	if p.err != nil {
+
+		return
+	}
+
+	found := "the end"
+	if p.at < len(p.words) {
+
+		found = strconv.Quote(p.words[p.at])
+	}
+
+	p.err = fmt.Errorf("selection: expected %s, found %s", expected, found)
+}
+
+func isSelectionKeyword(word string) bool {
+
+	switch word {
+	case "and", "or", "not", "(", ")":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+// A kind of body, with the properties bodies of that kind get by default
+type Species struct {
+	Name                 string
+	Mass, Charge, Radius float64
+}
+
+// The properties of a body other than it's state and mass
+type properties struct {
+	id      int
+	species *Species
+
+	charge, radius float64
+
+	// The axes along which the body does not move
+	frozen [3]bool
+
+	attrs map[string]interface{}
+}
+
+// Pair potentials between bodies of given species, by the names of the two
+// species. The order of the names does not matter.
+type SpeciesPairs map[[2]string]PairPotential
+
+// Set the potential between the two species
+func (sp SpeciesPairs) Set(a, b string, p PairPotential) {
+
+	sp[[2]string{a, b}] = p
+}
+
+// The potential between the two species, or nil if they don't interact
+func (sp SpeciesPairs) Between(a, b string) PairPotential {
+
+	if p, ok := sp[[2]string{a, b}]; ok {
+
+		return p
+	}
+
+	return sp[[2]string{b, a}]
+}
@@ -0,0 +1,223 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"io"
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// Stillinger-Weber parameters for a triplet of elements, named like in LAMMPS
+type SWParams struct {
+	Epsilon, Sigma float64
+	// The cutoff, in units of Sigma (a in LAMMPS)
+	Cut           float64
+	Lambda, Gamma float64
+	CosTheta0     float64
+	A, B, P, Q    float64
+	Tol           float64
+}
+
+// The distance beyond which the bodies do not interact
+func (p SWParams) Cutoff() float64 {
+
+	return p.Cut * p.Sigma
+}
+
+// The two-body energy at separation r and it's derivative
+func (p SWParams) twoBody(r float64) (e, dEdr float64) {
+
+	sp, sq := math.Pow(p.Sigma/r, p.P), math.Pow(p.Sigma/r, p.Q)
+
+	x := math.Exp(p.Sigma / (r - p.Cutoff()))
+
+	e = p.A * p.Epsilon * (p.B*sp - sq) * x
+	dEdr = p.A * p.Epsilon * x * ((-p.P*p.B*sp+p.Q*sq)/r - (p.B*sp-sq)*p.Sigma/math.Pow(r-p.Cutoff(), 2))
+
+	return e, dEdr
+}
+
+// The radial factor of the three-body energy for a leg of length r and
+// the derivative of it's logarithm
+func (p SWParams) leg(r float64) (x, dLogX float64) {
+
+	d := r - p.Cutoff()
+
+	return math.Exp(p.Gamma * p.Sigma / d), -p.Gamma * p.Sigma / (d * d)
+}
+
+// The three-body Stillinger-Weber potential for covalent solids like silicon
+//
+//	E = sum_i<j phi2(r_ij) + sum_i sum_j<k phi3(r_ij, r_ik, theta_jik)
+//
+// All the forces are computed at once when the potential is prepared -- Accel
+// panics unless that happened since the bodies last moved.
+type StillingerWeber struct {
+	Elements []string
+	// Parameters for all the triplets of elements, see Param
+	Params []SWParams
+	// The element of each body, as an index into Elements. When nil, all the
+	// bodies are of the first element.
+	Types []int
+	// When not nil, neighbours are looked up in it instead of a cell list
+	Neighbours *NeighbourList
+
+	manyBody
+}
+
+// Reads a Stillinger-Weber potential for the given elements from a LAMMPS sw
+// parameter file
+func ReadStillingerWeber(r io.Reader, elements ...string) (*StillingerWeber, error) {
+
+	n := len(elements)
+
+	sw := &StillingerWeber{
+		Elements: elements,
+		Params:   make([]SWParams, n*n*n),
+	}
+
+	err := readTriplets(r, 11, elements, func(i, j, k int, ps []float64) {
+
+		sw.Params[tripletAt(n, i, j, k)] = SWParams{
+			Epsilon: ps[0], Sigma: ps[1], Cut: ps[2],
+			Lambda: ps[3], Gamma: ps[4], CosTheta0: ps[5],
+			A: ps[6], B: ps[7], P: ps[8], Q: ps[9], Tol: ps[10],
+		}
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+// The parameters for the triplet of elements i, j, k
+func (sw *StillingerWeber) Param(i, j, k int) SWParams {
+
+	return sw.Params[tripletAt(len(sw.Elements), i, j, k)]
+}
+
+// Compute the forces on all the bodies
+func (sw *StillingerWeber) Prepare(bs []*Body) {
+
+	_, f, _ := sw.evaluate(bs, neighboursFor(sw.Neighbours, bs, sw.cutoff()))
+
+	sw.store(bs, f)
+}
+
+func (sw *StillingerWeber) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	return sw.accel("sw", bs, i)
+}
+
+func (sw *StillingerWeber) Energy(bs []*Body) float64 {
+
+	u, _, _ := sw.evaluate(bs, neighbours(bs, sw.cutoff()))
+
+	return u
+}
+
+// The virial of all the interactions
+func (sw *StillingerWeber) Virial(bs []*Body) Tensor {
+
+	_, _, w := sw.evaluate(bs, neighbours(bs, sw.cutoff()))
+
+	return w
+}
+
+// The largest cutoff among all the element pairs
+func (sw *StillingerWeber) cutoff() float64 {
+
+	rc := 0.0
+
+	for _, p := range sw.Params {
+
+		rc = math.Max(rc, p.Cutoff())
+	}
+
+	return rc
+}
+
+// The total energy, the forces on each body and the virial with the given
+// neighbours
+func (sw *StillingerWeber) evaluate(bs []*Body, nbs [][]int) (u float64, f []vect.Vector, w Tensor) {
+
+	f = make([]vect.Vector, len(bs))
+
+	for i := range bs {
+
+		ti := elementOf(sw.Types, i)
+
+		for at, j := range nbs[i] {
+
+			tj := elementOf(sw.Types, j)
+
+			pij := sw.Param(ti, tj, tj)
+
+			u1, r1 := separation(bs, i, j).UnitAndNorm()
+			if r1 >= pij.Cutoff() {
+				continue
+			}
+
+			if i < j {
+
+				e, dEdr := pij.twoBody(r1)
+
+				u += e
+				addPair(f, &w, i, j, u1, r1, dEdr)
+			}
+
+			x1, dLogX1 := pij.leg(r1)
+
+			for _, k := range nbs[i][at+1:] {
+
+				tk := elementOf(sw.Types, k)
+
+				pik := sw.Param(ti, tk, tk)
+
+				u2, r2 := separation(bs, i, k).UnitAndNorm()
+				if r2 >= pik.Cutoff() {
+					continue
+				}
+
+				x2, dLogX2 := pik.leg(r2)
+
+				pijk := sw.Param(ti, tj, tk)
+
+				cos := u1.Dot(u2)
+				h := cos - pijk.CosTheta0
+				strength := pijk.Lambda * pijk.Epsilon * x1 * x2
+
+				e := strength * h * h
+
+				u += e
+				addTriplet(
+					f, &w, i, j, k, u1, u2, r1, r2, cos,
+					e*dLogX1, e*dLogX2, 2*strength*h,
+				)
+			}
+		}
+	}
+
+	return u, f, w
+}
+
+// Follows a body being added. When Types is set, the new body is of the first
+// element.
+func (sw *StillingerWeber) BodyAdded(i int) {
+
+	sw.Types = insertAt(sw.Types, i)
+	sw.forget()
+}
+
+// Follows a body being removed
+func (sw *StillingerWeber) BodyRemoved(i int) {
+
+	sw.Types = removeAt(sw.Types, i)
+	sw.forget()
+}
@@ -5,6 +5,9 @@
 package newton
 
 import (
+	"runtime"
+	"sync"
+
 	"github.com/szabba/md/vect"
 )
 
@@ -13,6 +16,52 @@ type System struct {
 	algo   Integrator
 	bodies []*Body
 	force  Force
+
+	// The states of all the bodies, one after another, and the accelerations
+	// computed for them
+	xs, vs, as []vect.Vector
+	masses     []float64
+	props      []properties
+
+	// The identifier the next body added will get
+	nextID int
+
+	t    float64
+	step int
+	box  Box
+
+	// The length of the latest step
+	dt float64
+
+	skin  float64
+	lists []*NeighbourList
+
+	boundaries []Boundary
+	observers  []periodic
+
+	checkpointed []Checkpointed
+
+	rng   *RNG
+	units Units
+	kB    float64
+
+	driftEvery int
+
+	workers int
+
+	// The accelerations due to each part of a split force's pass
+	partAs [][]vect.Vector
+}
+
+// A condition the system enforces on the bodies after each step, like a hard
+// wall
+type Boundary interface {
+	Enforce(sys *System)
+}
+
+// Anything that keeps track of simulation time
+type Clock interface {
+	Time() float64
 }
 
 // Construct an empty system that will use the given integrator and has space
@@ -22,16 +71,56 @@ func NewSystem(algo Integrator, bodyCount int) *System {
 	sys := new(System)
 
 	sys.algo = algo
+	sys.skin = DefaultSkin
+	sys.box = Open
+	sys.rng = NewRNG(0)
+	sys.units = Reduced
+	sys.kB = Reduced.Boltzmann
+
+	n := algo.StateLen()
+
+	sys.xs = make([]vect.Vector, bodyCount*n)
+	sys.vs = make([]vect.Vector, bodyCount*n)
+	sys.as = make([]vect.Vector, bodyCount)
+	sys.masses = make([]float64, bodyCount)
+	sys.props = make([]properties, bodyCount)
+
+	for i := range sys.props {
+
+		sys.props[i].id = i
+	}
+	sys.nextID = bodyCount
+
+	views := make([]Body, bodyCount)
 
 	sys.bodies = make([]*Body, bodyCount)
 	for i, _ := range sys.bodies {
 
-		sys.bodies[i] = NewBody(sys.algo)
+		views[i] = bodyIn(sys.algo, nil, nil, nil, nil)
+		sys.bodies[i] = &views[i]
 	}
 
+	sys.bind()
+
 	return sys
 }
 
+// Point the bodies at their parts of the system's storage
+func (sys *System) bind() {
+
+	n := sys.algo.StateLen()
+
+	for i, b := range sys.bodies {
+
+		lo, hi := i*n, (i+1)*n
+
+		b.Xs = sys.xs[lo:hi:hi]
+		b.Vs = sys.vs[lo:hi:hi]
+		b.mass = &sys.masses[i]
+		b.props = &sys.props[i]
+	}
+}
+
 // Set the system force
 func (sys *System) SetForce(f Force) {
 
@@ -51,20 +140,391 @@ func (sys *System) AddForce(f Force) {
 	}
 }
 
-// Perform an integration step with the given dt
+// Perform an integration step with the given dt, calling the observers due
+// along the way
 func (sys *System) Step(dt float64) {
 
-	as := make([]vect.Vector, len(sys.bodies))
+	sys.dt = dt
 
-	for i, _ := range sys.bodies {
+	sys.observe(BeforeForces)
+
+	as := sys.accelerate(dt)
+
+	sys.observe(AfterForces)
+
+	sys.forEachBody(func(i int) {
 
-		as[i] = sys.force.Accel(sys.bodies, i, dt)
+		integrate(sys.algo, sys.bodies[i], as[i], dt)
+	})
+
+	for _, b := range sys.boundaries {
+
+		b.Enforce(sys)
 	}
 
-	for i, body := range sys.bodies {
+	sys.t += dt
+	sys.step++
 
-		sys.algo.Integrate(body, as[i], dt)
+	if sys.driftEvery > 0 && sys.step%sys.driftEvery == 0 {
+
+		sys.RemoveDrift(dt)
 	}
+
+	sys.observe(AfterStep)
+}
+
+// Compute the accelerations of the bodies at their latest states. The forces
+// give them in units of force per mass, which are converted to units of
+// acceleration.
+func (sys *System) accelerate(dt float64) []vect.Vector {
+
+	as := sys.as
+
+	if sys.force == nil {
+
+		for i := range as {
+
+			as[i] = vect.Zero
+		}
+
+		return as
+	}
+
+	prepare(sys.force, sys.bodies)
+
+	if sf, ok := sys.force.(SplitForce); ok {
+
+		sys.addSplitAccels(sf, dt)
+
+	} else if bf, ok := sys.force.(BatchForce); ok {
+
+		for i := range as {
+
+			as[i] = vect.Zero
+		}
+
+		bf.AddAccels(sys.bodies, as, dt, nil)
+
+	} else {
+
+		sys.forEachBody(func(i int) {
+
+			as[i] = sys.force.Accel(sys.bodies, i, dt)
+		})
+	}
+
+	if e := sys.mvv2e(); e != 1 {
+
+		sys.forEachBody(func(i int) {
+
+			as[i] = as[i].Scale(1 / e)
+		})
+	}
+
+	return as
+}
+
+// The number of bodies per part of a split force's pass, and the most parts
+// there can be
+const (
+	bodiesPerPart = 128
+	maxParts      = 64
+)
+
+// Computes the accelerations due to a split force, with the parts of it's
+// pass spread among the workers. The number of parts only depends on the
+// number of bodies and they are summed in a fixed order, so the results don't
+// change with the number of workers.
+func (sys *System) addSplitAccels(f SplitForce, dt float64) {
+
+	as := sys.as
+
+	parts := len(as) / bodiesPerPart
+	if parts > maxParts {
+
+		parts = maxParts
+	}
+
+	if parts < 2 {
+
+		for i := range as {
+
+			as[i] = vect.Zero
+		}
+
+		f.AddAccelsPart(sys.bodies, as, 0, 1, dt, nil)
+
+		return
+	}
+
+	for len(sys.partAs) < parts {
+
+		sys.partAs = append(sys.partAs, nil)
+	}
+
+	sys.forEach(parts, func(part int) {
+
+		buf := sys.partAs[part]
+		if cap(buf) < len(as) {
+
+			buf = make([]vect.Vector, len(as))
+		}
+		buf = buf[:len(as)]
+
+		for i := range buf {
+
+			buf[i] = vect.Zero
+		}
+
+		f.AddAccelsPart(sys.bodies, buf, part, parts, dt, nil)
+
+		sys.partAs[part] = buf
+	})
+
+	sys.forEachBody(func(i int) {
+
+		a := vect.Zero
+
+		for _, buf := range sys.partAs[:parts] {
+
+			a = a.Plus(buf[i])
+		}
+
+		as[i] = a
+	})
+}
+
+// Set the latest positions and velocities of all the bodies, and let the
+// integrator fill in the history it needs for steps of dt from them and the
+// accelerations due to the system force
+//
+// This is the way to set up the bodies that works whatever the integrator.
+func (sys *System) Initialize(xs, vs []vect.Vector, dt float64) {
+
+	for i, b := range sys.bodies {
+
+		b.Xs[0], b.Vs[0] = xs[i], vs[i]
+		b.wrap()
+	}
+
+	as := sys.accelerate(dt)
+
+	for i, b := range sys.bodies {
+
+		Bootstrap(sys.algo, b, b.Xs[0], b.Vs[0], as[i], dt)
+	}
+}
+
+// The pressure tensor, from the current velocities of the bodies and the
+// virial of the system force
+//
+//	P = (sum_i m_i v_i (x) v_i + W) / V
+//
+// with the kinetic part converted to units of energy. Forces that don't
+// implement Virial are left out. In a box that isn't periodic in all
+// directions the pressure is zero.
+func (sys *System) PressureTensor() Tensor {
+
+	var p Tensor
+
+	for _, b := range sys.bodies {
+
+		v := b.VNow()
+
+		p = p.Plus(Outer(v, v).Scale(b.Mass() * sys.mvv2e()))
+	}
+
+	if v, ok := sys.force.(Virial); ok {
+
+		p = p.Plus(v.Virial(sys.bodies))
+	}
+
+	return p.Scale(1 / sys.box.Volume())
+}
+
+// Compute the accelerations and integrate the bodies in parallel, splitting
+// them evenly among the given number of goroutines. When workers is not
+// positive, GOMAXPROCS goroutines are used. A single worker means stepping
+// serially.
+//
+// The results do not depend on the number of workers, but the forces have to
+// be safe to call Accel on concurrently once they are prepared. Split forces
+// have the parts of their pass computed concurrently, other batch forces
+// compute the accelerations in one pass and only the integration is split.
+func (sys *System) SetParallel(workers int) {
+
+	if workers <= 0 {
+
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	sys.workers = workers
+}
+
+// The number of goroutines stepping the system
+func (sys *System) Workers() int {
+
+	if sys.workers < 1 {
+
+		return 1
+	}
+
+	return sys.workers
+}
+
+// Calls do with the index of every body, splitting the bodies into contiguous
+// chunks among the workers
+func (sys *System) forEachBody(do func(i int)) {
+
+	sys.forEach(len(sys.bodies), do)
+}
+
+// Calls do with every index below n, splitting them into contiguous chunks
+// among the workers
+func (sys *System) forEach(n int, do func(i int)) {
+
+	workers := sys.Workers()
+
+	if workers == 1 || n < 2 {
+
+		for i := 0; i < n; i++ {
+
+			do(i)
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	chunk := (n + workers - 1) / workers
+
+	for lo := 0; lo < n; lo += chunk {
+
+		hi := lo + chunk
+		if hi > n {
+
+			hi = n
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+
+			defer wg.Done()
+
+			for i := lo; i < hi; i++ {
+
+				do(i)
+			}
+		}(lo, hi)
+	}
+
+	wg.Wait()
+}
+
+// Add a boundary enforced after each step
+func (sys *System) AddBoundary(b Boundary) {
+
+	sys.boundaries = append(sys.boundaries, b)
+}
+
+// Add a body of mass m at x moving with velocity v, after all the others
+//
+// The history the integrator needs for steps of dt is bootstrapped from x
+// and v as if no force was acting on the body. The
+// system force is told about the new body if it is a Tracker. When it is a
+// copy of one instead, like a Hooke set by value, AddBody panics.
+func (sys *System) AddBody(x, v vect.Vector, m, dt float64) *Body {
+
+	n := sys.algo.StateLen()
+
+	sys.xs = append(sys.xs, make([]vect.Vector, n)...)
+	sys.vs = append(sys.vs, make([]vect.Vector, n)...)
+
+	sys.as = append(sys.as, vect.Zero)
+	sys.masses = append(sys.masses, m)
+	sys.props = append(sys.props, properties{id: sys.nextID})
+
+	sys.nextID++
+
+	b := bodyIn(sys.algo, nil, nil, nil, nil)
+	b.box = sys.box
+
+	sys.bodies = append(sys.bodies, &b)
+
+	// The storage might have moved, so all the bodies get pointed at it anew
+	sys.bind()
+
+	b.Xs[0] = x
+	b.wrap()
+
+	Bootstrap(sys.algo, &b, b.Xs[0], v, vect.Zero, dt)
+
+	if sys.force != nil {
+
+		bodyAdded(sys.force, len(sys.bodies)-1)
+	}
+
+	return &b
+}
+
+// Remove the i-th body from the system. The bodies after it move down by one
+// index.
+//
+// The system force is told about the removal if it is a Tracker. When it is a
+// copy of one instead, like a Hooke set by value, RemoveBody panics.
+func (sys *System) RemoveBody(i int) {
+
+	// The removed body keeps it's state, detached from the system
+	sys.bodies[i].detach()
+
+	n := sys.algo.StateLen()
+
+	sys.xs = append(sys.xs[:i*n], sys.xs[(i+1)*n:]...)
+	sys.vs = append(sys.vs[:i*n], sys.vs[(i+1)*n:]...)
+	sys.as = append(sys.as[:i], sys.as[i+1:]...)
+	sys.masses = append(sys.masses[:i], sys.masses[i+1:]...)
+	sys.props = append(sys.props[:i], sys.props[i+1:]...)
+
+	sys.bodies = append(sys.bodies[:i], sys.bodies[i+1:]...)
+
+	sys.bind()
+
+	if sys.force != nil {
+
+		bodyRemoved(sys.force, i)
+	}
+}
+
+// Put the bodies in a box, wrapping those outside of it back in
+func (sys *System) SetBox(box Box) {
+
+	sys.box = box
+
+	for _, b := range sys.bodies {
+
+		b.box = box
+		b.wrap()
+	}
+}
+
+// The box the bodies are in
+func (sys *System) Box() Box {
+
+	return sys.box
+}
+
+// The simulation time of the latest body positions, at which forces are
+// evaluated
+func (sys *System) Time() float64 {
+
+	return sys.t
+}
+
+// Set the simulation time of the latest body positions
+func (sys *System) SetTime(t float64) {
+
+	sys.t = t
 }
 
 // The number of bodies in the system
@@ -78,3 +538,102 @@ func (sys *System) Body(i int) *Body {
 
 	return sys.bodies[i]
 }
+
+// The indices of the bodies the selection picks out
+func (sys *System) Select(s Selection) []int {
+
+	return Select(s, sys.bodies)
+}
+
+// Freeze the selected bodies along all axes
+func (sys *System) Freeze(s Selection) {
+
+	for _, i := range sys.Select(s) {
+
+		sys.bodies[i].Freeze()
+	}
+}
+
+// Tie each of the selected bodies to it's latest position with a harmonic
+// spring of stiffness k. The restraints are returned so that they can be
+// added to the system force.
+func (sys *System) Restrain(s Selection, k float64) *Restraints {
+
+	var rs Restraints
+
+	for _, i := range sys.Select(s) {
+
+		rs = append(rs, Restraint{I: i, Point: sys.bodies[i].XLatest(), K: k})
+	}
+
+	return &rs
+}
+
+// The random number generator of the system. It is seeded with zero until
+// told otherwise and saved in checkpoints.
+func (sys *System) RNG() *RNG {
+
+	return sys.rng
+}
+
+// The random number stream of the i-th body at the current step, for the
+// given purpose. It depends on the body's ID, not it's index, so it doesn't
+// change as other bodies are added or removed.
+func (sys *System) BodyStream(i int, purpose uint64) *Stream {
+
+	return sys.rng.Stream(streamBody, purpose, uint64(sys.props[i].id), uint64(sys.step))
+}
+
+// The index of the body with the given ID, or -1 if there is no such body in
+// the system
+func (sys *System) IndexOf(id int) int {
+
+	for i := range sys.props {
+
+		if sys.props[i].id == id {
+
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Set the skin of the neighbour lists created afterwards
+func (sys *System) SetSkin(skin float64) {
+
+	sys.skin = skin
+}
+
+// A Verlet neighbour list for the given cutoff
+//
+// All the forces asking for the same cutoff share one list. It is kept up to
+// date by the forces using it, when they are prepared.
+func (sys *System) NeighbourList(cutoff float64) *NeighbourList {
+
+	for _, nl := range sys.lists {
+
+		if nl.Cutoff() == cutoff && nl.Skin() == sys.skin {
+
+			return nl
+		}
+	}
+
+	nl := NewNeighbourList(cutoff, sys.skin)
+	sys.lists = append(sys.lists, nl)
+
+	return nl
+}
+
+// The total number of times the neighbour lists of the system were rebuilt
+func (sys *System) NeighbourRebuilds() int {
+
+	n := 0
+
+	for _, nl := range sys.lists {
+
+		n += nl.Rebuilds()
+	}
+
+	return n
+}
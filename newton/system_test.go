@@ -0,0 +1,398 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szabba/md/vect"
+)
+
+// A Lennard-Jones gas of n bodies in a periodic box
+func ljGas(n int, seed int64) *System {
+
+	rng := rand.New(rand.NewSource(seed))
+
+	sys := NewSystem(Verlet, n)
+	sys.SetBox(NewOrthorhombic(vect.NewVector(8, 8, 8)))
+	sys.SetForce(&PairForce{
+		Potential: Shifted(LennardJones{Epsilon: 1, Sigma: 1}, 2.5),
+		Cutoff:    2.5,
+	})
+
+	side := 1
+	for side*side*side < n {
+
+		side++
+	}
+
+	for i := 0; i < n; i++ {
+
+		x := vect.NewVector(
+			float64(i%side), float64(i/side%side), float64(i/side/side),
+		).Scale(8 / float64(side))
+		v := vect.NewVector(rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64())
+
+		b := sys.Body(i)
+		b.SetMass(1)
+		b.Shift(x.Minus(v.Scale(0.005)), v)
+		b.Shift(x, v)
+	}
+
+	return sys
+}
+
+func TestParallelSteppingIsDeterministic(t *testing.T) {
+
+	serial, parallel := ljGas(125, 1), ljGas(125, 1)
+	parallel.SetParallel(7)
+
+	for i := 0; i < 50; i++ {
+
+		serial.Step(0.005)
+		parallel.Step(0.005)
+	}
+
+	for i := 0; i < serial.Bodies(); i++ {
+
+		if serial.Body(i).XNow() != parallel.Body(i).XNow() {
+
+			t.Fatalf(
+				"body %d is at %v when stepping serially and %v in parallel",
+				i, serial.Body(i).XNow(), parallel.Body(i).XNow(),
+			)
+		}
+	}
+}
+
+// A force without any effect, that notes the most parts of a split pass it
+// saw being computed at once
+type overlapProbe struct {
+	active, most int32
+}
+
+func (p *overlapProbe) Accel(bs []*Body, i int, dt float64) vect.Vector {
+
+	return vect.Zero
+}
+
+func (p *overlapProbe) AddAccels(bs []*Body, as []vect.Vector, dt float64, tally *Tally) {
+
+	p.AddAccelsPart(bs, as, 0, 1, dt, tally)
+}
+
+// Waits a while for another part to overlap with this one, unless that
+// already happened
+func (p *overlapProbe) AddAccelsPart(bs []*Body, as []vect.Vector, part, parts int, dt float64, tally *Tally) {
+
+	n := atomic.AddInt32(&p.active, 1)
+	defer atomic.AddInt32(&p.active, -1)
+
+	for {
+		most := atomic.LoadInt32(&p.most)
+		if n <= most || atomic.CompareAndSwapInt32(&p.most, most, n) {
+			break
+		}
+	}
+
+	for end := time.Now().Add(time.Second); atomic.LoadInt32(&p.most) < 2 && time.Now().Before(end); {
+
+		runtime.Gosched()
+	}
+}
+
+func TestSplitForcesRunInParallel(t *testing.T) {
+
+	serial, parallel := ljGas(512, 2), ljGas(512, 2)
+
+	forces := func() SumForce {
+
+		lj := &PairForce{
+			Potential: Shifted(LennardJones{Epsilon: 1, Sigma: 1}, 2.5),
+			Cutoff:    2.5,
+		}
+		bonds := Bonds{{I: 0, J: 1, Potential: Harmonic{K: 1, R0: 1}}}
+
+		return SumForce{lj, bonds, NewPicky(lj, 3)}
+	}
+
+	probe := new(overlapProbe)
+
+	serial.SetForce(forces())
+	parallel.SetForce(append(forces(), probe))
+	parallel.SetParallel(4)
+
+	for i := 0; i < 10; i++ {
+
+		serial.Step(0.005)
+		parallel.Step(0.005)
+	}
+
+	if probe.most < 2 {
+
+		t.Errorf("the parts of the force should be computed in parallel")
+	}
+
+	for i := 0; i < serial.Bodies(); i++ {
+
+		if serial.Body(i).XNow() != parallel.Body(i).XNow() {
+
+			t.Fatalf(
+				"body %d is at %v when stepping serially and %v in parallel",
+				i, serial.Body(i).XNow(), parallel.Body(i).XNow(),
+			)
+		}
+	}
+}
+
+func TestAddedBodyMovesUniformly(t *testing.T) {
+
+	for name, algo := range map[string]Integrator{"Euler": Euler, "Verlet": Verlet} {
+
+		sys := NewSystem(algo, 0)
+		sys.SetForce(SumForce{})
+
+		x, v := vect.NewVector(1, 2, 3), vect.NewVector(0.5, 0, -1)
+
+		b := sys.AddBody(x, v, 2, 0.1)
+
+		for i := 0; i < 10; i++ {
+
+			sys.Step(0.1)
+		}
+
+		if want := x.Plus(v.Scale(1)); !near(b.XLatest(), want) {
+
+			t.Errorf("%s: the added body should be at %v not %v", name, want, b.XLatest())
+		}
+	}
+}
+
+func TestForcesFollowRemovedBodies(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+
+	for i := 0; i < 4; i++ {
+
+		sys.AddBody(vect.NewVector(float64(i), 0, 0), vect.Zero, 1, 0.1)
+	}
+
+	bonds := Bonds{{I: 0, J: 1}, {I: 1, J: 2}, {I: 2, J: 3}}
+	h := &Hooke{Springs: [][]Spring{
+		{{}, {K: 1}, {}, {}},
+		{{K: 1}, {}, {K: 2}, {}},
+		{{}, {K: 2}, {}, {K: 3}},
+		{{}, {}, {K: 3}, {}},
+	}}
+	picky := NewPicky(h, 3).(*PickyForce)
+
+	sys.SetForce(SumForce{&bonds, picky})
+
+	sys.RemoveBody(1)
+
+	if want := (Bonds{{I: 1, J: 2}}); len(bonds) != 1 || bonds[0] != want[0] {
+
+		t.Errorf("the bonds should be %v not %v", want, bonds)
+	}
+
+	if len(picky.zeroFor) != 1 || picky.zeroFor[0] != 2 {
+
+		t.Errorf("the picky force should ignore body 2, not %v", picky.zeroFor)
+	}
+
+	if len(h.Springs) != 3 || h.Springs[1][2].K != 3 || h.Springs[0][1].K != 0 {
+
+		t.Errorf("the springs should follow the removed body, not %v", h.Springs)
+	}
+
+	sys.AddBody(vect.Zero, vect.Zero, 1, 0.1)
+
+	if len(h.Springs) != 4 || len(h.Springs[3]) != 4 || h.Springs[1][2].K != 3 {
+
+		t.Errorf("the springs should follow the added body, not %v", h.Springs)
+	}
+}
+
+func TestIDsSurviveRemoval(t *testing.T) {
+
+	sys := NewSystem(Verlet, 3)
+	sys.RemoveBody(0)
+
+	b := sys.AddBody(vect.Zero, vect.Zero, 1, 0.1)
+
+	if sys.Body(0).ID() != 1 || sys.Body(1).ID() != 2 || b.ID() != 3 {
+
+		t.Fatalf(
+			"the IDs should be 1, 2, 3 not %d, %d, %d",
+			sys.Body(0).ID(), sys.Body(1).ID(), b.ID(),
+		)
+	}
+
+	if i := sys.IndexOf(2); i != 1 {
+
+		t.Fatalf("the body with ID 2 should be at index 1 not %d", i)
+	}
+}
+
+func TestForcesBySpecies(t *testing.T) {
+
+	argon := &Species{Name: "Ar", Mass: 40}
+	neon := &Species{Name: "Ne", Mass: 20}
+
+	sys := NewSystem(Verlet, 0)
+	for i, s := range []*Species{argon, argon, neon} {
+
+		b := sys.AddBody(vect.NewVector(float64(i), 0, 0), vect.Zero, 0, 0.1)
+		b.SetSpecies(s)
+	}
+
+	pairs := SpeciesPairs{}
+	pairs.Set("Ar", "Ne", Harmonic{K: 1, R0: 0})
+
+	pf := &PairForce{Pairs: pairs}
+	bs := []*Body{sys.Body(0), sys.Body(1), sys.Body(2)}
+
+	// Argon atoms do not interact with each other
+	if a := pf.Accel(bs, 1, 0.1); !near(a, vect.NewVector(1.0/40, 0, 0)) {
+
+		t.Errorf("the second argon atom should accelerate by %v not %v", vect.NewVector(1.0/40, 0, 0), a)
+	}
+
+	picky := NewPickySpecies(pf, "Ne")
+
+	if a := picky.Accel(bs, 2, 0.1); a != vect.Zero {
+
+		t.Errorf("the neon atom should be ignored, not accelerate by %v", a)
+	}
+}
+
+func TestFrozenBodiesStayPut(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+
+	pinned := sys.AddBody(vect.Zero, vect.NewVector(1, 1, 1), 1, 0.1)
+	sliding := sys.AddBody(vect.UnitX, vect.NewVector(1, 1, 1), 1, 0.1)
+
+	pinned.Freeze()
+	sliding.SetFrozen(false, true, true)
+
+	far := vect.NewVector(10, 10, 10)
+	sys.SetForce(Restraints{{I: 0, Point: far, K: 1}, {I: 1, Point: far, K: 1}})
+
+	for i := 0; i < 10; i++ {
+
+		sys.Step(0.1)
+	}
+
+	if pinned.XLatest() != vect.Zero || pinned.VNow() != vect.Zero {
+
+		t.Errorf("the frozen body should stay at rest, not be at %v with %v", pinned.XLatest(), pinned.VNow())
+	}
+
+	x := sliding.XLatest()
+	if x.Dot(vect.UnitY) != 0 || x.Dot(vect.UnitZ) != 0 || x.Dot(vect.UnitX) <= 2 {
+
+		t.Errorf("the body frozen along y and z should only move along x, not get to %v", x)
+	}
+}
+
+func TestRestraintsPullBack(t *testing.T) {
+
+	sys := NewSystem(Verlet, 0)
+	b := sys.AddBody(vect.Zero, vect.Zero, 1, 0.1)
+
+	rs := sys.Restrain(All, 4)
+	sys.SetForce(rs)
+
+	b.Xs[0] = vect.UnitX
+
+	if u := rs.Energy([]*Body{b}); u != 2 {
+
+		t.Fatalf("the restraint energy should be 2 not %g", u)
+	}
+
+	sys.RemoveBody(0)
+
+	if len(*rs) != 0 {
+
+		t.Fatalf("the restraint should go away with the body, not stay as %v", *rs)
+	}
+}
+
+func TestInitializeBootstrapsVerlet(t *testing.T) {
+
+	// A harmonic oscillator, with x(t) = cos(t)
+	sys := NewSystem(Verlet, 1)
+	sys.Body(0).SetMass(1)
+	sys.SetForce(Restraints{{I: 0, Point: vect.Zero, K: 1}})
+
+	dt := 0.01
+	sys.Initialize([]vect.Vector{vect.UnitX}, []vect.Vector{vect.Zero}, dt)
+
+	if err := sys.Run(context.Background(), dt, 100); err != nil {
+
+		t.Fatal(err)
+	}
+
+	if x, want := sys.Body(0).XLatest(), vect.UnitX.Scale(math.Cos(1)); x.Minus(want).Norm() > 1e-5 {
+
+		t.Errorf("the oscillator should get to %v, not %v", want, x)
+	}
+}
+
+func TestForcesSetByValueCannotFollowBodies(t *testing.T) {
+
+	springs := [][]Spring{{{}, {K: 1}}, {{K: 1}, {}}}
+	bonds := Bonds{{I: 0, J: 1, Potential: Harmonic{K: 1}}}
+	restraints := Restraints{{I: 1, K: 1}}
+
+	forces := []struct {
+		name  string
+		force Force
+		ok    bool
+	}{
+		{"Hooke", Hooke{Springs: springs}, false},
+		{"Bonds", bonds, false},
+		{"Restraints", restraints, false},
+		{"summed Restraints", SumForce{restraints}, false},
+		{"*Hooke", &Hooke{Springs: springs}, true},
+		{"*Bonds", &bonds, true},
+		{"*Restraints", &restraints, true},
+	}
+
+	for _, f := range forces {
+
+		sys := NewSystem(Verlet, 2)
+		sys.Body(0).SetMass(1)
+		sys.Body(1).SetMass(1)
+		sys.SetForce(f.force)
+
+		panicked := func() (panicked bool) {
+
+			defer func() { panicked = recover() != nil }()
+
+			sys.AddBody(vect.UnitX, vect.Zero, 1, 0.1)
+			sys.Step(0.1)
+
+			return false
+		}()
+
+		if panicked && f.ok {
+
+			t.Errorf("adding a body should work with a %s force", f.name)
+		}
+
+		if !panicked && !f.ok {
+
+			t.Errorf("adding a body should panic with a %s force", f.name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+// A function tabulated at equally spaced points, starting from zero
+type table struct {
+	step   float64
+	values []float64
+}
+
+// The value and the derivative of the tabulated function at x
+//
+// Between the points the function is interpolated with cubic Hermite
+// polynomials. Past the last point it is extrapolated linearly.
+func (tb table) at(x float64) (y, dy float64) {
+
+	n := len(tb.values)
+
+	if x < 0 {
+
+		x = 0
+	}
+
+	u := x / tb.step
+	k := int(u)
+
+	if k >= n-1 {
+
+		slope := tb.slope(n - 1)
+
+		return tb.values[n-1] + slope*(x-float64(n-1)*tb.step), slope
+	}
+
+	p := u - float64(k)
+	p2, p3 := p*p, p*p*p
+
+	y0, y1 := tb.values[k], tb.values[k+1]
+	m0, m1 := tb.slope(k)*tb.step, tb.slope(k+1)*tb.step
+
+	y = (2*p3-3*p2+1)*y0 + (p3-2*p2+p)*m0 + (-2*p3+3*p2)*y1 + (p3-p2)*m1
+	dy = (6*p2-6*p)*y0 + (3*p2-4*p+1)*m0 + (-6*p2+6*p)*y1 + (3*p2-2*p)*m1
+
+	return y, dy / tb.step
+}
+
+// The derivative at the k-th point, estimated with finite differences
+func (tb table) slope(k int) float64 {
+
+	vs, n := tb.values, len(tb.values)
+
+	switch {
+	case n == 1:
+		return 0
+	case k == 0:
+		return (vs[1] - vs[0]) / tb.step
+	case k == n-1:
+		return (vs[n-1] - vs[n-2]) / tb.step
+	}
+
+	return (vs[k+1] - vs[k-1]) / (2 * tb.step)
+}
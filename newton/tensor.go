@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"github.com/szabba/md/vect"
+)
+
+// A second rank tensor in three dimensions, like the pressure tensor
+type Tensor [3][3]float64
+
+// The outer product of two vectors
+func Outer(a, b vect.Vector) (t Tensor) {
+
+	as, bs := components(a), components(b)
+
+	for i := range t {
+		for j := range t[i] {
+
+			t[i][j] = as[i] * bs[j]
+		}
+	}
+
+	return t
+}
+
+// Add two tensors
+func (t Tensor) Plus(u Tensor) Tensor {
+
+	for i := range t {
+		for j := range t[i] {
+
+			t[i][j] += u[i][j]
+		}
+	}
+
+	return t
+}
+
+// Scale a tensor by s
+func (t Tensor) Scale(s float64) Tensor {
+
+	for i := range t {
+		for j := range t[i] {
+
+			t[i][j] *= s
+		}
+	}
+
+	return t
+}
+
+// A force that can tell it's contribution to the virial tensor
+//
+//	W = sum_i x_i (x) F_i
+//
+// which for pair forces is the sum over pairs of the separation times the
+// force between them.
+type Virial interface {
+	Force
+	Virial(bs []*Body) Tensor
+}
+
+// The virial of a central pair interaction with an energy derivative dUdr,
+// between bodies separated by d
+func pairVirial(d vect.Vector, dUdr float64) Tensor {
+
+	dir, r := d.UnitAndNorm()
+
+	return Outer(dir, dir).Scale(-dUdr * r)
+}
@@ -0,0 +1,301 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"io"
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// Tersoff parameters for a triplet of elements, named like in LAMMPS
+type TersoffParams struct {
+	M, Gamma, Lambda3 float64
+	// The angular parameters c, d and cos(theta0)
+	C, D, CosTheta0 float64
+	N, Beta         float64
+	Lambda2, B      float64
+	// The cutoff goes smoothly to zero between R - BigD and R + BigD
+	R, BigD    float64
+	Lambda1, A float64
+}
+
+// The distance beyond which the bodies do not interact
+func (p TersoffParams) Cutoff() float64 {
+
+	return p.R + p.BigD
+}
+
+// The smooth cutoff function and it's derivative
+func (p TersoffParams) fc(r float64) (fc, dfc float64) {
+
+	switch {
+	case r < p.R-p.BigD:
+		return 1, 0
+	case r > p.R+p.BigD:
+		return 0, 0
+	}
+
+	arg := math.Pi / 2 * (r - p.R) / p.BigD
+
+	return (1 - math.Sin(arg)) / 2, -math.Pi / (4 * p.BigD) * math.Cos(arg)
+}
+
+// The repulsive pair term and it's derivative
+func (p TersoffParams) fR(r float64) (fR, dfR float64) {
+
+	fR = p.A * math.Exp(-p.Lambda1*r)
+
+	return fR, -p.Lambda1 * fR
+}
+
+// The attractive pair term and it's derivative
+func (p TersoffParams) fA(r float64) (fA, dfA float64) {
+
+	fA = -p.B * math.Exp(-p.Lambda2*r)
+
+	return fA, -p.Lambda2 * fA
+}
+
+// The angular term and it's derivative with respect to the cosine
+func (p TersoffParams) g(cos float64) (g, dg float64) {
+
+	c2, d2 := p.C*p.C, p.D*p.D
+	h := cos - p.CosTheta0
+
+	g = p.Gamma * (1 + c2/d2 - c2/(d2+h*h))
+
+	return g, p.Gamma * c2 * 2 * h / math.Pow(d2+h*h, 2)
+}
+
+// The exponential dependence of zeta on the difference of the bond lengths
+// and it's derivative
+func (p TersoffParams) exp(delta float64) (e, de float64) {
+
+	x := p.Lambda3 * delta
+
+	e = math.Exp(math.Pow(x, p.M))
+
+	return e, e * p.M * p.Lambda3 * math.Pow(x, p.M-1)
+}
+
+// The bond order for a given zeta and it's derivative
+func (p TersoffParams) bondOrder(zeta float64) (b, db float64) {
+
+	if zeta <= 0 {
+
+		return 1, 0
+	}
+
+	x := math.Pow(p.Beta*zeta, p.N)
+
+	b = math.Pow(1+x, -1/(2*p.N))
+
+	return b, -b / (1 + x) * x / (2 * zeta)
+}
+
+// The bond-order Tersoff potential for covalent solids like silicon and
+// carbon
+//
+//	E = 1/2 sum_i sum_j fc(r_ij) [fR(r_ij) + b_ij fA(r_ij)]
+//
+// where the bond order b_ij depends on the angles between the i-j bond and
+// the other bonds of the i-th body. All the forces are computed at once when
+// the potential is prepared -- Accel panics unless that happened since the
+// bodies last moved.
+type Tersoff struct {
+	Elements []string
+	// Parameters for all the triplets of elements, see Param
+	Params []TersoffParams
+	// The element of each body, as an index into Elements. When nil, all the
+	// bodies are of the first element.
+	Types []int
+	// When not nil, neighbours are looked up in it instead of a cell list
+	Neighbours *NeighbourList
+
+	manyBody
+}
+
+// Reads a Tersoff potential for the given elements from a LAMMPS tersoff
+// parameter file
+func ReadTersoff(r io.Reader, elements ...string) (*Tersoff, error) {
+
+	n := len(elements)
+
+	t := &Tersoff{
+		Elements: elements,
+		Params:   make([]TersoffParams, n*n*n),
+	}
+
+	err := readTriplets(r, 14, elements, func(i, j, k int, ps []float64) {
+
+		t.Params[tripletAt(n, i, j, k)] = TersoffParams{
+			M: ps[0], Gamma: ps[1], Lambda3: ps[2],
+			C: ps[3], D: ps[4], CosTheta0: ps[5],
+			N: ps[6], Beta: ps[7], Lambda2: ps[8], B: ps[9],
+			R: ps[10], BigD: ps[11], Lambda1: ps[12], A: ps[13],
+		}
+	})
+	if err != nil {
+
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// The parameters for the triplet of elements i, j, k
+func (t *Tersoff) Param(i, j, k int) TersoffParams {
+
+	return t.Params[tripletAt(len(t.Elements), i, j, k)]
+}
+
+// Compute the forces on all the bodies
+func (t *Tersoff) Prepare(bs []*Body) {
+
+	_, f, _ := t.evaluate(bs, neighboursFor(t.Neighbours, bs, t.cutoff()))
+
+	t.store(bs, f)
+}
+
+func (t *Tersoff) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	return t.accel("tersoff", bs, i)
+}
+
+func (t *Tersoff) Energy(bs []*Body) float64 {
+
+	u, _, _ := t.evaluate(bs, neighbours(bs, t.cutoff()))
+
+	return u
+}
+
+// The virial of all the interactions
+func (t *Tersoff) Virial(bs []*Body) Tensor {
+
+	_, _, w := t.evaluate(bs, neighbours(bs, t.cutoff()))
+
+	return w
+}
+
+// The largest cutoff among all the element triplets
+func (t *Tersoff) cutoff() float64 {
+
+	rc := 0.0
+
+	for _, p := range t.Params {
+
+		rc = math.Max(rc, p.Cutoff())
+	}
+
+	return rc
+}
+
+// The total energy, the forces on each body and the virial with the given
+// neighbours
+func (t *Tersoff) evaluate(bs []*Body, nbs [][]int) (u float64, f []vect.Vector, w Tensor) {
+
+	f = make([]vect.Vector, len(bs))
+
+	for i := range bs {
+
+		ti := elementOf(t.Types, i)
+
+		for _, j := range nbs[i] {
+
+			tj := elementOf(t.Types, j)
+
+			pij := t.Param(ti, tj, tj)
+
+			u1, r1 := separation(bs, i, j).UnitAndNorm()
+			if r1 >= pij.Cutoff() {
+				continue
+			}
+
+			zeta := 0.0
+			t.eachAngle(bs, nbs[i], i, j, u1, r1, func(k int, pijk TersoffParams, u2 vect.Vector, r2, cos float64) {
+
+				fc, _ := pijk.fc(r2)
+				g, _ := pijk.g(cos)
+				e, _ := pijk.exp(r1 - r2)
+
+				zeta += fc * g * e
+			})
+
+			fc, dfc := pij.fc(r1)
+			fR, dfR := pij.fR(r1)
+			fA, dfA := pij.fA(r1)
+			b, db := pij.bondOrder(zeta)
+
+			u += fc * (fR + b*fA) / 2
+			addPair(f, &w, i, j, u1, r1, (dfc*(fR+b*fA)+fc*(dfR+b*dfA))/2)
+
+			dEdzeta := fc * fA / 2 * db
+			if dEdzeta == 0 {
+				continue
+			}
+
+			t.eachAngle(bs, nbs[i], i, j, u1, r1, func(k int, pijk TersoffParams, u2 vect.Vector, r2, cos float64) {
+
+				fc, dfc := pijk.fc(r2)
+				g, dg := pijk.g(cos)
+				e, de := pijk.exp(r1 - r2)
+
+				addTriplet(
+					f, &w, i, j, k, u1, u2, r1, r2, cos,
+					dEdzeta*fc*g*de,
+					dEdzeta*(dfc*g*e-fc*g*de),
+					dEdzeta*fc*dg*e,
+				)
+			})
+		}
+	}
+
+	return u, f, w
+}
+
+// Calls do for every neighbour k of the i-th body within the cutoff, other
+// than the j-th one, passing along the parameters for the triplet, the unit vector
+// from the i-th to the k-th body, their separation and the cosine of the
+// angle jik
+func (t *Tersoff) eachAngle(
+	bs []*Body, nbs []int, i, j int, u1 vect.Vector, r1 float64,
+	do func(k int, pijk TersoffParams, u2 vect.Vector, r2, cos float64),
+) {
+
+	ti, tj := elementOf(t.Types, i), elementOf(t.Types, j)
+
+	for _, k := range nbs {
+
+		if k == j {
+			continue
+		}
+
+		pijk := t.Param(ti, tj, elementOf(t.Types, k))
+
+		u2, r2 := separation(bs, i, k).UnitAndNorm()
+		if r2 >= pijk.Cutoff() {
+			continue
+		}
+
+		do(k, pijk, u2, r2, u1.Dot(u2))
+	}
+}
+
+// Follows a body being added. When Types is set, the new body is of the first
+// element.
+func (t *Tersoff) BodyAdded(i int) {
+
+	t.Types = insertAt(t.Types, i)
+	t.forget()
+}
+
+// Follows a body being removed
+func (t *Tersoff) BodyRemoved(i int) {
+
+	t.Types = removeAt(t.Types, i)
+	t.forget()
+}
@@ -0,0 +1,199 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// Set the Boltzmann constant relating temperatures to energies. It is the one
+// of the system's units until told otherwise.
+func (sys *System) SetBoltzmann(kB float64) {
+
+	sys.kB = kB
+}
+
+// The Boltzmann constant relating temperatures to energies
+func (sys *System) Boltzmann() float64 {
+
+	return sys.kB
+}
+
+// Set the units all the quantities of the system are in, together with the
+// Boltzmann constant. The values already in the system are not converted.
+func (sys *System) SetUnits(u Units) {
+
+	sys.units = u
+	sys.kB = u.Boltzmann
+}
+
+// The units all the quantities of the system are in. They are reduced
+// Lennard-Jones units until told otherwise.
+func (sys *System) Units() Units {
+
+	return sys.units
+}
+
+// The energy of a unit mass times a unit velocity squared
+func (sys *System) mvv2e() float64 {
+
+	if sys.units.MVV2E == 0 {
+
+		return 1
+	}
+
+	return sys.units.MVV2E
+}
+
+// Draw the velocities of the bodies from the Maxwell-Boltzmann distribution
+// at the given temperature, using a new stream of the system's generator
+//
+// The momentum of the centre of mass is removed. When exact is true, the
+// velocities are then scaled so that the kinetic temperature is exactly the
+// given one. Frozen bodies are left at rest.
+//
+// The bodies keep their latest positions. Their histories are bootstrapped
+// for steps of dt, as with Initialize.
+func (sys *System) MaxwellBoltzmann(temperature, dt float64, exact bool) {
+
+	s := sys.rng.Next()
+
+	vs := make([]vect.Vector, len(sys.bodies))
+
+	for i, b := range sys.bodies {
+
+		sigma := math.Sqrt(sys.kB * temperature / (b.Mass() * sys.mvv2e()))
+
+		var v [3]float64
+		for k, frozen := range b.props.frozen {
+
+			if !frozen {
+
+				v[k] = sigma * s.NormFloat64()
+			}
+		}
+
+		vs[i] = fromComponents(v)
+	}
+
+	sys.removeDrift(vs)
+
+	if t := sys.temperatureOf(vs); exact && t > 0 {
+
+		scale := math.Sqrt(temperature / t)
+
+		for i := range vs {
+
+			vs[i] = vs[i].Scale(scale)
+		}
+	}
+
+	xs := make([]vect.Vector, len(sys.bodies))
+
+	for i, b := range sys.bodies {
+
+		xs[i] = b.XLatest()
+	}
+
+	sys.Initialize(xs, vs, dt)
+}
+
+// The kinetic temperature of the bodies, from their latest velocities
+//
+// Each coordinate along which a body is not frozen is a degree of freedom,
+// less one for every direction in which the centre of mass can move.
+func (sys *System) Temperature() float64 {
+
+	vs := make([]vect.Vector, len(sys.bodies))
+
+	for i, b := range sys.bodies {
+
+		vs[i] = b.VLatest()
+	}
+
+	return sys.temperatureOf(vs)
+}
+
+func (sys *System) temperatureOf(vs []vect.Vector) float64 {
+
+	dof, twiceKinetic := 0, 0.0
+
+	for _, free := range sys.freeAlong() {
+
+		if free > 1 {
+
+			dof += free - 1
+		}
+	}
+
+	for i, b := range sys.bodies {
+
+		twiceKinetic += b.Mass() * vs[i].Dot(vs[i])
+	}
+
+	if dof == 0 {
+
+		return 0
+	}
+
+	return twiceKinetic * sys.mvv2e() / (float64(dof) * sys.kB)
+}
+
+// The number of bodies free to move along each axis
+func (sys *System) freeAlong() [3]int {
+
+	var free [3]int
+
+	for _, b := range sys.bodies {
+
+		for k, frozen := range b.props.frozen {
+
+			if !frozen {
+
+				free[k]++
+			}
+		}
+	}
+
+	return free
+}
+
+// Remove the velocity of the centre of mass from the given velocities, along
+// each axis among the bodies free to move along it
+func (sys *System) removeDrift(vs []vect.Vector) {
+
+	var p, m [3]float64
+
+	for i, b := range sys.bodies {
+
+		v := components(vs[i])
+
+		for k, frozen := range b.props.frozen {
+
+			if !frozen {
+
+				p[k] += b.Mass() * v[k]
+				m[k] += b.Mass()
+			}
+		}
+	}
+
+	for i, b := range sys.bodies {
+
+		v := components(vs[i])
+
+		for k, frozen := range b.props.frozen {
+
+			if !frozen && m[k] > 0 {
+
+				v[k] -= p[k] / m[k]
+			}
+		}
+
+		vs[i] = fromComponents(v)
+	}
+}
@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func TestMaxwellBoltzmann(t *testing.T) {
+
+	sys := ljGas(1000, 1)
+	sys.SetBoltzmann(2)
+
+	sys.MaxwellBoltzmann(1.5, 0.005, false)
+
+	if temp := sys.Temperature(); math.Abs(temp-1.5) > 0.1 {
+
+		t.Errorf("the temperature should be near 1.5, not %g", temp)
+	}
+
+	sys.MaxwellBoltzmann(1.5, 0.005, true)
+
+	if temp := sys.Temperature(); math.Abs(temp-1.5) > 1e-9 {
+
+		t.Errorf("the temperature should be exactly 1.5, not %g", temp)
+	}
+
+	p := vect.Zero
+	for i := 0; i < sys.Bodies(); i++ {
+
+		b := sys.Body(i)
+
+		p = p.Plus(b.VLatest().Scale(b.Mass()))
+
+		// On the lattice the forces cancel out, so the history is a uniform
+		// motion
+		if want := b.XLatest().Minus(b.VLatest().Scale(0.005)); !near(b.XNow(), want) {
+
+			t.Fatalf("body %d should have been at %v a step before, not %v", i, want, b.XNow())
+		}
+	}
+
+	if p.Norm() > 1e-9 {
+
+		t.Errorf("the total momentum should be zero, not %v", p)
+	}
+}
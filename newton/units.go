@@ -0,0 +1,209 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A physical quantity, whose unit depends on the system of units
+type Quantity int
+
+const (
+	LengthUnit Quantity = iota
+	TimeUnit
+	MassUnit
+	EnergyUnit
+	TemperatureUnit
+	ChargeUnit
+
+	VelocityUnit
+	AccelerationUnit
+	ForceUnit
+	PressureUnit
+)
+
+// The number of base quantities, in terms of which the others are expressed
+const baseQuantities = int(ChargeUnit) + 1
+
+// The powers of the base quantities making up each quantity
+var dimensions = map[Quantity][baseQuantities]int{
+	LengthUnit:      {1, 0, 0, 0, 0, 0},
+	TimeUnit:        {0, 1, 0, 0, 0, 0},
+	MassUnit:        {0, 0, 1, 0, 0, 0},
+	EnergyUnit:      {0, 0, 0, 1, 0, 0},
+	TemperatureUnit: {0, 0, 0, 0, 1, 0},
+	ChargeUnit:      {0, 0, 0, 0, 0, 1},
+
+	VelocityUnit:     {1, -1, 0, 0, 0, 0},
+	AccelerationUnit: {1, -2, 0, 0, 0, 0},
+	ForceUnit:        {-1, 0, 0, 1, 0, 0},
+	PressureUnit:     {-3, 0, 0, 1, 0, 0},
+}
+
+// Physical constants in SI units (CODATA 2018)
+const (
+	boltzmannSI  = 1.380649e-23
+	coulombSI    = 8.9875517923e9
+	elementarySI = 1.602176634e-19
+	avogadro     = 6.02214076e23
+	calorieSI    = 4.184
+)
+
+// A system of units, with the constants that depend on it
+//
+// Like in LAMMPS, the units of energy need not be the units of mass times the
+// units of velocity squared -- the base units are given separately, and MVV2E
+// converts between the two.
+type Units struct {
+	Name string
+	// The energy of a unit mass times a unit velocity squared, in units of
+	// energy. Forces are divided by it to get accelerations. Zero is taken to
+	// mean one.
+	MVV2E float64
+	// The Boltzmann constant, in units of energy per temperature
+	Boltzmann float64
+	// The Coulomb constant 1 / (4 pi epsilon_0), in units of energy times
+	// length per charge squared
+	Coulomb float64
+
+	// The sizes of the base units in SI units and their symbols
+	sizes   [baseQuantities]float64
+	symbols [baseQuantities]string
+}
+
+var (
+	// SI units: m, s, kg, J, K, C
+	SI = Units{
+		Name:      "si",
+		MVV2E:     1,
+		Boltzmann: boltzmannSI,
+		Coulomb:   coulombSI,
+		sizes:     [...]float64{1, 1, 1, 1, 1, 1},
+		symbols:   [...]string{"m", "s", "kg", "J", "K", "C"},
+	}
+
+	// The real units of LAMMPS: Å, fs, g/mol, kcal/mol, K, e
+	Real = Units{
+		Name:      "real",
+		MVV2E:     1e-3 * 1e5 * 1e5 / (1e3 * calorieSI),
+		Boltzmann: boltzmannSI * avogadro / (1e3 * calorieSI),
+		Coulomb:   coulombSI * elementarySI * elementarySI * avogadro / (1e3 * calorieSI * 1e-10),
+		sizes: [...]float64{
+			1e-10, 1e-15, 1e-3 / avogadro, 1e3 * calorieSI / avogadro, 1, elementarySI,
+		},
+		symbols: [...]string{"Å", "fs", "g/mol", "kcal/mol", "K", "e"},
+	}
+
+	// The metal units of LAMMPS: Å, ps, g/mol, eV, K, e
+	Metal = Units{
+		Name:      "metal",
+		MVV2E:     1e-3 * 1e2 * 1e2 / (avogadro * elementarySI),
+		Boltzmann: boltzmannSI / elementarySI,
+		Coulomb:   coulombSI * elementarySI / 1e-10,
+		sizes: [...]float64{
+			1e-10, 1e-12, 1e-3 / avogadro, elementarySI, 1, elementarySI,
+		},
+		symbols: [...]string{"Å", "ps", "g/mol", "eV", "K", "e"},
+	}
+
+	// Reduced Lennard-Jones units with unknown sizes. Values in them can not
+	// be converted to other units -- use ReducedLJ for that.
+	Reduced = ReducedLJ(math.NaN(), math.NaN(), math.NaN())
+)
+
+// Reduced Lennard-Jones units, in which the length sigma (in m), the energy
+// epsilon (in J) and the mass (in kg) are all one, as are the Boltzmann and
+// Coulomb constants and MVV2E
+func ReducedLJ(sigma, epsilon, mass float64) Units {
+
+	return Units{
+		Name:      "reduced",
+		MVV2E:     1,
+		Boltzmann: 1,
+		Coulomb:   1,
+		sizes: [...]float64{
+			sigma,
+			sigma * math.Sqrt(mass/epsilon),
+			mass,
+			epsilon,
+			epsilon / boltzmannSI,
+			math.Sqrt(sigma * epsilon / coulombSI),
+		},
+		symbols: [...]string{"σ", "τ", "m", "ε", "ε/kB", "q*"},
+	}
+}
+
+// The units with the given name: si, real, metal or reduced
+func UnitsNamed(name string) (Units, bool) {
+
+	for _, u := range []Units{SI, Real, Metal, Reduced} {
+
+		if u.Name == name {
+
+			return u, true
+		}
+	}
+
+	return Units{}, false
+}
+
+// The size of the unit of a quantity in SI units. It is NaN when not known.
+func (u Units) SI(q Quantity) float64 {
+
+	size := 1.0
+
+	for k, power := range dimensions[q] {
+
+		size *= math.Pow(u.sizes[k], float64(power))
+	}
+
+	return size
+}
+
+// The symbol of the unit of a quantity, eg. Å/fs
+func (u Units) Symbol(q Quantity) string {
+
+	var num, den []string
+
+	for k, power := range dimensions[q] {
+
+		switch {
+
+		case power == 1:
+			num = append(num, u.symbols[k])
+
+		case power > 1:
+			num = append(num, u.symbols[k]+"^"+strconv.Itoa(power))
+
+		case power == -1:
+			den = append(den, u.symbols[k])
+
+		case power < -1:
+			den = append(den, u.symbols[k]+"^"+strconv.Itoa(-power))
+		}
+	}
+
+	symbol := strings.Join(num, " ")
+	if symbol == "" {
+
+		symbol = "1"
+	}
+
+	for _, d := range den {
+
+		symbol += "/" + d
+	}
+
+	return symbol
+}
+
+// Express a value of a quantity given in one system of units in another
+func Convert(x float64, q Quantity, from, to Units) float64 {
+
+	return x * from.SI(q) / to.SI(q)
+}
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+func closeTo(x, want, relative float64) bool {
+
+	return math.Abs(x-want) <= relative*math.Abs(want)
+}
+
+func TestUnitConstants(t *testing.T) {
+
+	constants := []struct {
+		name      string
+		got, want float64
+	}{
+		{"real Boltzmann", Real.Boltzmann, 0.0019872043},
+		{"real Coulomb", Real.Coulomb, 332.06371},
+		{"metal Boltzmann", Metal.Boltzmann, 8.617333262e-5},
+		{"metal Coulomb", Metal.Coulomb, 14.399645},
+		{"real mvv2e", Real.MVV2E, 2390.0573},
+		{"metal mvv2e", Metal.MVV2E, 1.0364269e-4},
+		{"eV in kcal/mol", Convert(1, EnergyUnit, Metal, Real), 23.060548},
+		{"Å/fs in Å/ps", Convert(1, VelocityUnit, Real, Metal), 1000},
+		{"argon τ in ps", Convert(1, TimeUnit, ReducedLJ(3.4e-10, 1.65e-21, 6.63e-26), Metal), 2.155},
+	}
+
+	for _, c := range constants {
+
+		if !closeTo(c.got, c.want, 1e-3) {
+
+			t.Errorf("the %s should be %g not %g", c.name, c.want, c.got)
+		}
+	}
+
+	if !math.IsNaN(Convert(1, LengthUnit, Reduced, SI)) {
+
+		t.Errorf("reduced units of unknown size should not convert")
+	}
+}
+
+func TestUnitSymbols(t *testing.T) {
+
+	symbols := []struct{ got, want string }{
+		{Real.Symbol(VelocityUnit), "Å/fs"},
+		{Metal.Symbol(ForceUnit), "eV/Å"},
+		{SI.Symbol(PressureUnit), "J/m^3"},
+		{SI.Symbol(AccelerationUnit), "m/s^2"},
+	}
+
+	for _, s := range symbols {
+
+		if s.got != s.want {
+
+			t.Errorf("the symbol should be %q not %q", s.want, s.got)
+		}
+	}
+}
+
+func TestSystemUnits(t *testing.T) {
+
+	sys := NewSystem(Verlet, 1)
+
+	if sys.Units().Name != "reduced" || sys.Boltzmann() != 1 {
+
+		t.Fatalf("a new system should be in reduced units")
+	}
+
+	sys.SetUnits(Metal)
+
+	if sys.Boltzmann() != Metal.Boltzmann {
+
+		t.Errorf("the Boltzmann constant should be %g not %g", Metal.Boltzmann, sys.Boltzmann())
+	}
+}
+
+func TestMetalUnits(t *testing.T) {
+
+	const n = 1000
+
+	sys := NewSystem(Verlet, n)
+	sys.SetUnits(Metal)
+
+	for i := 0; i < n; i++ {
+
+		sys.Body(i).SetMass(39.948)
+	}
+
+	sys.MaxwellBoltzmann(300, 0.001, true)
+
+	squares := 0.0
+	for i := 0; i < n; i++ {
+
+		v := sys.Body(i).VLatest()
+		squares += v.Dot(v)
+	}
+
+	// The rms speed of argon at 300 K is about 433 m/s
+	if rms := math.Sqrt(squares / n); !closeTo(rms, 4.33, 1e-2) {
+
+		t.Errorf("the rms speed of argon at 300 K should be near 4.33 Å/ps, not %g", rms)
+	}
+
+	if e, want := sys.KineticEnergy(), 1.5*(n-1)*Metal.Boltzmann*300; !closeTo(e, want, 1e-9) {
+
+		t.Errorf("the kinetic energy should be %g eV not %g", want, e)
+	}
+
+	one := NewSystem(Verlet, 1)
+	one.SetUnits(Metal)
+	one.Body(0).SetMass(1)
+	one.SetForce(Restraints{{I: 0, Point: vect.NewVector(1, 0, 0), K: 1}})
+
+	one.Initialize([]vect.Vector{vect.Zero}, []vect.Vector{vect.Zero}, 0.001)
+
+	// A force of 1 eV/Å on 1 g/mol, in Å/ps^2
+	want := vect.NewVector(9648.533, 0, 0)
+	if a := one.as[0]; a.Minus(want).Norm() > 1e-6*want.Norm() {
+
+		t.Errorf("the acceleration should be 9648.533 Å/ps^2 along x, not %v", a)
+	}
+}
@@ -0,0 +1,145 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"io"
+
+	"github.com/szabba/md/vect"
+)
+
+// A read-only look at a system, given to observers
+type View struct {
+	sys *System
+	at  Event
+}
+
+// The event the view was taken at
+func (v View) Event() Event {
+
+	return v.at
+}
+
+// The simulation time of the latest body positions
+func (v View) Time() float64 {
+
+	return v.sys.Time()
+}
+
+// The number of steps performed so far
+func (v View) StepIndex() int {
+
+	return v.sys.StepIndex()
+}
+
+// The number of bodies in the system
+func (v View) Bodies() int {
+
+	return v.sys.Bodies()
+}
+
+// The units of the system
+func (v View) Units() Units {
+
+	return v.sys.Units()
+}
+
+// The box the bodies are in
+func (v View) Box() Box {
+
+	return v.sys.Box()
+}
+
+// The indices of the selected bodies
+func (v View) Select(s Selection) []int {
+
+	return v.sys.Select(s)
+}
+
+// The current position of the i-th body
+func (v View) X(i int) vect.Vector {
+
+	return v.sys.bodies[i].XNow()
+}
+
+// The current velocity of the i-th body
+func (v View) V(i int) vect.Vector {
+
+	return v.sys.bodies[i].VNow()
+}
+
+// The current position of the i-th body, as if it never got wrapped back into
+// the box
+func (v View) XUnwrapped(i int) vect.Vector {
+
+	return v.sys.bodies[i].XUnwrapped()
+}
+
+// The acceleration of the i-th body computed in the latest step. Before the
+// forces are computed, it is the one from the step before.
+func (v View) Accel(i int) vect.Vector {
+
+	return v.sys.as[i]
+}
+
+// The mass of the i-th body
+func (v View) Mass(i int) float64 {
+
+	return v.sys.bodies[i].Mass()
+}
+
+// The ID of the i-th body
+func (v View) ID(i int) int {
+
+	return v.sys.bodies[i].ID()
+}
+
+// The name of the species of the i-th body
+func (v View) SpeciesName(i int) string {
+
+	return v.sys.bodies[i].SpeciesName()
+}
+
+// The total kinetic energy of the bodies
+func (v View) KineticEnergy() float64 {
+
+	return v.sys.KineticEnergy()
+}
+
+// The potential energy of the system force
+func (v View) PotentialEnergy() float64 {
+
+	return v.sys.PotentialEnergy()
+}
+
+// The total momentum of the bodies
+func (v View) Momentum() vect.Vector {
+
+	return v.sys.Momentum()
+}
+
+// The angular momentum of the bodies about their centre of mass
+func (v View) AngularMomentum() vect.Vector {
+
+	return v.sys.AngularMomentum()
+}
+
+// The centre of mass of the bodies
+func (v View) CenterOfMass() vect.Vector {
+
+	return v.sys.CenterOfMass()
+}
+
+// The pressure tensor of the system
+func (v View) PressureTensor() Tensor {
+
+	return v.sys.PressureTensor()
+}
+
+// Writes a checkpoint of the system
+func (v View) WriteCheckpoint(w io.Writer) error {
+
+	return v.sys.WriteCheckpoint(w)
+}
@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+
+	"github.com/szabba/md/vect"
+)
+
+// A plane through Point. The bodies are meant to stay on the side Normal
+// points to.
+type Plane struct {
+	Point, Normal vect.Vector
+}
+
+// The signed distance of x from the plane, positive on the inner side
+func (p Plane) Distance(x vect.Vector) float64 {
+
+	return x.Minus(p.Point).Dot(p.Normal.Unit())
+}
+
+// The mirror image of the point x
+func (p Plane) reflect(x vect.Vector) vect.Vector {
+
+	return x.Minus(p.Normal.Unit().Scale(2 * p.Distance(x)))
+}
+
+// The mirror image of the vector v
+func (p Plane) reflectVector(v vect.Vector) vect.Vector {
+
+	n := p.Normal.Unit()
+
+	return v.Minus(n.Scale(2 * v.Dot(n)))
+}
+
+// A wall repelling bodies with the Lennard-Jones 9-3 potential of a half-space
+// filled with Lennard-Jones particles
+//
+//	U(d) = Epsilon [2/15 (Sigma/d)^9 - (Sigma/d)^3]
+//
+// The potential diverges at the wall, so bodies closer to it than a hundredth
+// of Sigma, or behind it, are treated as being that far in front of it.
+type LJ93Wall struct {
+	Plane
+	Epsilon, Sigma float64
+	// Bodies further from the wall do not feel it. Zero means there is no
+	// cutoff.
+	Cutoff float64
+}
+
+func (w LJ93Wall) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	b := bs[i]
+
+	d := w.distance(b.Xs[0])
+	if w.Cutoff > 0 && d >= w.Cutoff {
+
+		return vect.Zero
+	}
+
+	s3 := math.Pow(w.Sigma/d, 3)
+	dUdd := w.Epsilon * (-18./15*s3*s3*s3 + 3*s3) / d
+
+	return w.Normal.Unit().Scale(-dUdd / b.Mass())
+}
+
+// The distance of x from the wall, kept away from zero
+func (w LJ93Wall) distance(x vect.Vector) float64 {
+
+	return math.Max(w.Distance(x), w.Sigma/100)
+}
+
+// The energy of all the bodies' interaction with the wall
+func (w LJ93Wall) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for _, b := range bs {
+
+		d := w.distance(b.Xs[0])
+		if w.Cutoff > 0 && d >= w.Cutoff {
+			continue
+		}
+
+		s3 := math.Pow(w.Sigma/d, 3)
+
+		u += w.Epsilon * (2./15*s3*s3*s3 - s3)
+	}
+
+	return u
+}
+
+// A wall pushing back bodies closer to it than Range with a harmonic force
+//
+//	U(d) = K/2 (Range - d)^2
+type HarmonicWall struct {
+	Plane
+	K, Range float64
+}
+
+func (w HarmonicWall) Accel(bs []*Body, i int, dt float64) (a vect.Vector) {
+
+	b := bs[i]
+
+	d := w.Distance(b.Xs[0])
+	if d >= w.Range {
+
+		return vect.Zero
+	}
+
+	return w.Normal.Unit().Scale(w.K * (w.Range - d) / b.Mass())
+}
+
+// The energy of all the bodies' interaction with the wall
+func (w HarmonicWall) Energy(bs []*Body) float64 {
+
+	u := 0.0
+
+	for _, b := range bs {
+
+		if d := w.Distance(b.Xs[0]); d < w.Range {
+
+			u += w.K * math.Pow(w.Range-d, 2) / 2
+		}
+	}
+
+	return u
+}
+
+// A wall reflecting bodies that cross it specularly
+//
+// The latest velocity is reflected. When the current state is the latest one,
+// so is the position. Verlet keeps the current position a step behind the
+// latest; it is left in front of the wall and the latest position rebuilt
+// from it with the reflected velocity. That is the velocity Verlet infers from
+// the two, and the current position is never behind the wall.
+type ReflectingWall struct {
+	Plane
+}
+
+func (w ReflectingWall) Enforce(sys *System) {
+
+	for i := 0; i < sys.Bodies(); i++ {
+
+		b := sys.Body(i)
+
+		if w.Distance(b.Xs[0]) >= 0 {
+			continue
+		}
+
+		v := w.reflectVector(b.Vs[0])
+
+		if b.currAt == 0 {
+
+			b.Xs[0], b.Vs[0] = w.reflect(b.Xs[0]), v
+			continue
+		}
+
+		b.Xs[0] = b.XNow().Plus(v.Scale(float64(b.currAt) * sys.dt))
+		b.Vs[0] = v
+	}
+}
+
+// A wall that removes the bodies crossing it from the system
+type AbsorbingWall struct {
+	Plane
+	absorbed int
+}
+
+// Constructs an absorbing wall
+func NewAbsorbingWall(p Plane) *AbsorbingWall {
+
+	return &AbsorbingWall{Plane: p}
+}
+
+// The number of bodies absorbed so far
+func (w *AbsorbingWall) Absorbed() int {
+
+	return w.absorbed
+}
+
+func (w *AbsorbingWall) Enforce(sys *System) {
+
+	for i := sys.Bodies() - 1; i >= 0; i-- {
+
+		if w.Distance(sys.Body(i).Xs[0]) < 0 {
+
+			sys.RemoveBody(i)
+			w.absorbed++
+		}
+	}
+}
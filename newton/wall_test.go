@@ -0,0 +1,120 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package newton
+
+import (
+	"math"
+	"testing"
+
+	"github.com/szabba/md/vect"
+)
+
+var floor = Plane{Point: vect.Zero, Normal: vect.UnitZ.Scale(2)}
+
+func TestSoftWallForcesMatchEnergy(t *testing.T) {
+
+	bs := bodiesAt(
+		vect.NewVector(0, 0, 0.9),
+		vect.NewVector(1, 2, 1.3),
+		vect.NewVector(0, 0, 3),
+	)
+
+	forceMatchesEnergy(LJ93Wall{Plane: floor, Epsilon: 1, Sigma: 1, Cutoff: 2.5}, bs, t)
+	forceMatchesEnergy(HarmonicWall{Plane: floor, K: 10, Range: 1.5}, bs, t)
+}
+
+func TestLJ93WallIsFiniteBehindTheWall(t *testing.T) {
+
+	w := LJ93Wall{Plane: floor, Epsilon: 1, Sigma: 1}
+	bs := bodiesAt(vect.Zero, vect.NewVector(0, 0, -1))
+
+	for i := range bs {
+
+		a := w.Accel(bs, i, 0.1)
+
+		if a.Dot(vect.UnitZ) <= 0 || math.IsInf(a.Norm(), 0) || math.IsNaN(a.Norm()) {
+
+			t.Errorf("the wall should push body %d away with a finite force, not %v", i, a)
+		}
+	}
+
+	if u := w.Energy(bs); math.IsInf(u, 0) || math.IsNaN(u) {
+
+		t.Errorf("the energy should be finite, not %g", u)
+	}
+}
+
+// A system of a single body moving with velocity v towards walls
+func towardsWalls(v vect.Vector, walls ...Boundary) *System {
+
+	sys := NewSystem(Verlet, 1)
+	sys.SetForce(LinearDrag{})
+
+	for _, w := range walls {
+
+		sys.AddBoundary(w)
+	}
+
+	b := sys.Body(0)
+	b.SetMass(1)
+
+	dt := 0.1
+	x := vect.NewVector(0, 0, 1)
+
+	b.Shift(x.Minus(v.Scale(dt)), v)
+	b.Shift(x, v)
+
+	return sys
+}
+
+func TestReflectingWall(t *testing.T) {
+
+	v := vect.NewVector(1, 0, -1)
+
+	sys := towardsWalls(v, ReflectingWall{floor})
+	b := sys.Body(0)
+
+	for i := 0; i < 20; i++ {
+
+		sys.Step(0.1)
+
+		if floor.Distance(b.XNow()) < 0 {
+
+			t.Fatalf("the current position should stay above the floor, not be %v at step %d", b.XNow(), i)
+		}
+	}
+
+	reflected := vect.NewVector(1, 0, 1)
+
+	if !near(b.VNow(), reflected) || !near(b.VLatest(), reflected) {
+
+		t.Fatalf("velocity should be %v not %v", reflected, b.VNow())
+	}
+
+	if floor.Distance(b.XLatest()) < 0 {
+
+		t.Fatalf("the body should stay above the floor, not at %v", b.XLatest())
+	}
+}
+
+func TestAbsorbingWall(t *testing.T) {
+
+	wall := NewAbsorbingWall(floor)
+
+	sys := towardsWalls(vect.NewVector(0, 0, -1), wall)
+
+	for i := 0; i < 20; i++ {
+
+		sys.Step(0.1)
+	}
+
+	if sys.Bodies() != 0 || wall.Absorbed() != 1 {
+
+		t.Fatalf(
+			"the body should have been absorbed, there are %d bodies left and %d absorbed",
+			sys.Bodies(), wall.Absorbed(),
+		)
+	}
+}